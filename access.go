@@ -0,0 +1,242 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	SATISFY_ALL = "all"
+	SATISFY_ANY = "any"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AccessPolicy contains effective access control configuration of location
+type AccessPolicy struct {
+	Rules        []*AccessRule
+	AuthBasic    string // Realm (empty if auth_basic is off)
+	AuthUserFile string
+	AuthRequest  string // URI of auth_request subrequest (empty if off)
+	Satisfy      string
+}
+
+// AccessRule contains allow/deny rule
+type AccessRule struct {
+	Allow   bool
+	All     bool
+	Unix    bool
+	Network *net.IPNet
+
+	err error // Parsing error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetAccessPolicy returns effective access policy of location (with values
+// inherited from server and HTTP block)
+func (l *Location) GetAccessPolicy() (*AccessPolicy, error) {
+	if l == nil {
+		return nil, fmt.Errorf("Location is nil")
+	}
+
+	policy := &AccessPolicy{Satisfy: SATISFY_ALL}
+
+	for _, rule := range l.getAccessRules() {
+		if rule.err != nil {
+			return nil, rule.err
+		}
+
+		policy.Rules = append(policy.Rules, rule)
+	}
+
+	switch satisfy := l.getAccessValue("satisfy"); satisfy {
+	case "", SATISFY_ALL:
+	case SATISFY_ANY:
+		policy.Satisfy = SATISFY_ANY
+	default:
+		return nil, fmt.Errorf("Unsupported satisfy value %s", satisfy)
+	}
+
+	if authBasic := l.getAccessValue("auth_basic"); authBasic != "off" {
+		policy.AuthBasic = unquote(authBasic)
+	}
+
+	if authRequest := l.getAccessValue("auth_request"); authRequest != "off" {
+		policy.AuthRequest = authRequest
+	}
+
+	policy.AuthUserFile = l.getAccessValue("auth_basic_user_file")
+
+	return policy, nil
+}
+
+// ParseAccessRule parses allow or deny directive
+func ParseAccessRule(directive, value string) (*AccessRule, error) {
+	rule := &AccessRule{}
+
+	switch directive {
+	case "allow":
+		rule.Allow = true
+	case "deny":
+	default:
+		return nil, fmt.Errorf("Unsupported access directive %s", directive)
+	}
+
+	switch value {
+	case "all":
+		rule.All = true
+	case "unix:":
+		rule.Unix = true
+	default:
+		network, err := parseGeoNetwork(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s rule: %v", directive, err)
+		}
+
+		rule.Network = network
+	}
+
+	return rule, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Allowed returns true if client with given address (nil for unix socket
+// clients) can access location. Authenticated flag means that client passed
+// auth_basic/auth_request checks.
+func (p *AccessPolicy) Allowed(ip net.IP, authenticated bool) bool {
+	if p == nil {
+		return true
+	}
+
+	matched, allow := p.checkRules(ip)
+	requiresAuth := p.RequiresAuth()
+
+	if p.Satisfy == SATISFY_ANY {
+		switch {
+		case matched && allow, requiresAuth && authenticated:
+			return true
+		case matched && !allow, requiresAuth:
+			return false
+		}
+
+		return true
+	}
+
+	return (!matched || allow) && (!requiresAuth || authenticated)
+}
+
+// CheckAddress returns true if client with given address is not denied by
+// allow/deny rules (first matched rule wins)
+func (p *AccessPolicy) CheckAddress(ip net.IP) bool {
+	matched, allow := p.checkRules(ip)
+	return !matched || allow
+}
+
+// RequiresAuth returns true if policy requires authentication
+func (p *AccessPolicy) RequiresAuth() bool {
+	return p != nil && (p.AuthBasic != "" || p.AuthRequest != "")
+}
+
+// Match returns true if rule matches given address (nil for unix socket)
+func (r *AccessRule) Match(ip net.IP) bool {
+	switch {
+	case r.All:
+		return true
+	case ip == nil:
+		return r.Unix
+	case r.Network != nil:
+		return r.Network.Contains(ip)
+	}
+
+	return false
+}
+
+// String returns rule as a string
+func (r *AccessRule) String() string {
+	directive := "deny"
+
+	if r.Allow {
+		directive = "allow"
+	}
+
+	switch {
+	case r.All:
+		return directive + " all"
+	case r.Unix:
+		return directive + " unix:"
+	}
+
+	return directive + " " + r.Network.String()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// checkRules returns true if any of rules matched given address and result of
+// matched rule
+func (p *AccessPolicy) checkRules(ip net.IP) (bool, bool) {
+	if p == nil {
+		return false, false
+	}
+
+	ip = normalizeIP(ip)
+
+	for _, rule := range p.Rules {
+		if rule.Match(ip) {
+			return true, rule.Allow
+		}
+	}
+
+	return false, false
+}
+
+// getAccessRules returns the most specific list of allow/deny rules
+func (l *Location) getAccessRules() []*AccessRule {
+	switch {
+	case len(l.Access) != 0:
+		return l.Access
+	case l.Parent == nil:
+		return nil
+	case len(l.Parent.Access) != 0:
+		return l.Parent.Access
+	case l.Parent.Parent == nil:
+		return nil
+	}
+
+	return l.Parent.Parent.Access
+}
+
+// getAccessValue returns the last unconditional value of property with given
+// name from the most specific level where property is defined
+func (l *Location) getAccessValue(name string) string {
+	values := l.getInheritedValues(name)
+
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// unquote removes quotes from value
+func unquote(value string) string {
+	args := parseArgs(value)
+
+	if len(args) == 0 {
+		return ""
+	}
+
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,174 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"net"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const accessTestConfig = `
+deny 10.0.0.1;
+auth_basic_user_file conf/htpasswd;
+
+server {
+  listen 80;
+  server_name access.domain.com;
+
+  auth_basic "Restricted area";
+
+  location / {
+  }
+
+  location /admin/ {
+    deny  192.168.1.1;
+    allow 192.168.1.0/24;
+    allow 2001:db8::/32;
+    allow unix:;
+    deny  all;
+  }
+
+  location /any/ {
+    satisfy any;
+    allow 127.0.0.1;
+    deny  all;
+  }
+
+  location /public/ {
+    auth_basic off;
+  }
+
+  location /sso/ {
+    auth_basic off;
+    auth_request /auth;
+    satisfy any;
+  }
+}
+`
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestAccessPolicy(c *C) {
+	file := c.MkDir() + "/access.conf"
+	c.Assert(ioutil.WriteFile(file, []byte(accessTestConfig), 0644), IsNil)
+
+	http, err := ReadPart(file, "")
+
+	c.Assert(err, IsNil)
+	c.Assert(http.Access, HasLen, 1)
+	c.Assert(http.Access[0].String(), Equals, "deny 10.0.0.1/32")
+
+	server := http.Servers[0]
+	getPolicy := func(uri string) (*AccessPolicy, error) {
+		location, err := server.MatchLocation(uri)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return location.GetAccessPolicy()
+	}
+
+	root, err := getPolicy("/")
+
+	c.Assert(err, IsNil)
+	c.Assert(root.Rules, HasLen, 1)
+	c.Assert(root.Rules[0].String(), Equals, "deny 10.0.0.1/32")
+	c.Assert(root.AuthBasic, Equals, "Restricted area")
+	c.Assert(root.AuthUserFile, Equals, "conf/htpasswd")
+	c.Assert(root.Satisfy, Equals, SATISFY_ALL)
+	c.Assert(root.RequiresAuth(), Equals, true)
+	c.Assert(root.Allowed(net.ParseIP("10.0.0.1"), true), Equals, false)
+	c.Assert(root.Allowed(net.ParseIP("10.0.0.2"), false), Equals, false)
+	c.Assert(root.Allowed(net.ParseIP("10.0.0.2"), true), Equals, true)
+
+	admin, err := getPolicy("/admin/")
+
+	c.Assert(err, IsNil)
+	c.Assert(admin.Rules, HasLen, 5)
+	c.Assert(admin.Rules[3].String(), Equals, "allow unix:")
+	c.Assert(admin.Rules[4].String(), Equals, "deny all")
+	c.Assert(admin.CheckAddress(net.ParseIP("10.0.0.1")), Equals, false)
+	c.Assert(admin.CheckAddress(net.ParseIP("192.168.1.1")), Equals, false)
+	c.Assert(admin.CheckAddress(net.ParseIP("192.168.1.2")), Equals, true)
+	c.Assert(admin.CheckAddress(net.ParseIP("::ffff:192.168.1.2")), Equals, true)
+	c.Assert(admin.CheckAddress(net.ParseIP("2001:db8::1")), Equals, true)
+	c.Assert(admin.CheckAddress(nil), Equals, true)
+	c.Assert(admin.Allowed(net.ParseIP("192.168.1.2"), false), Equals, false)
+	c.Assert(admin.Allowed(net.ParseIP("192.168.1.2"), true), Equals, true)
+
+	any, err := getPolicy("/any/")
+
+	c.Assert(err, IsNil)
+	c.Assert(any.Satisfy, Equals, SATISFY_ANY)
+	c.Assert(any.Allowed(net.ParseIP("127.0.0.1"), false), Equals, true)
+	c.Assert(any.Allowed(net.ParseIP("10.0.0.2"), true), Equals, true)
+	c.Assert(any.Allowed(net.ParseIP("10.0.0.2"), false), Equals, false)
+
+	public, err := getPolicy("/public/")
+
+	c.Assert(err, IsNil)
+	c.Assert(public.RequiresAuth(), Equals, false)
+	c.Assert(public.Allowed(net.ParseIP("10.0.0.2"), false), Equals, true)
+	c.Assert(public.Allowed(net.ParseIP("10.0.0.1"), true), Equals, false)
+
+	sso, err := getPolicy("/sso/")
+
+	c.Assert(err, IsNil)
+	c.Assert(sso.AuthRequest, Equals, "/auth")
+	c.Assert(sso.Allowed(net.ParseIP("10.0.0.2"), false), Equals, false)
+	c.Assert(sso.Allowed(net.ParseIP("10.0.0.1"), true), Equals, true)
+
+	policy := &AccessPolicy{Satisfy: SATISFY_ANY}
+	c.Assert(policy.Allowed(net.ParseIP("10.0.0.1"), false), Equals, true)
+
+	policy.Rules = []*AccessRule{{Allow: true, All: true}}
+	c.Assert(policy.Allowed(nil, false), Equals, true)
+
+	policy = nil
+	c.Assert(policy.Allowed(nil, false), Equals, true)
+	c.Assert(policy.CheckAddress(nil), Equals, true)
+	c.Assert(policy.RequiresAuth(), Equals, false)
+
+	location := &Location{
+		Properties: &ConditionalProperties{Data: map[string][]ConditionalProperty{}},
+		Access:     appendAccessDirective(nil, "allow", "999.0.0.1"),
+	}
+
+	_, err = location.GetAccessPolicy()
+	c.Assert(err, NotNil)
+
+	location.Access = nil
+	location.Parent = &Server{Properties: &ConditionalProperties{Data: map[string][]ConditionalProperty{
+		"auth_basic": {{-1, "First"}, {-1, "Second"}, {0, "Conditional"}},
+	}}}
+	location.Properties.Data["satisfy"] = []ConditionalProperty{{-1, "any"}, {-1, "all"}, {0, "any"}}
+
+	policy, err = location.GetAccessPolicy()
+
+	c.Assert(err, IsNil)
+	c.Assert(policy.Satisfy, Equals, SATISFY_ALL)
+	c.Assert(policy.AuthBasic, Equals, "Second")
+
+	location.Properties.Data["satisfy"] = []ConditionalProperty{{-1, "some"}}
+
+	_, err = location.GetAccessPolicy()
+	c.Assert(err, NotNil)
+
+	_, err = (*Location)(nil).GetAccessPolicy()
+	c.Assert(err, NotNil)
+
+	_, err = ParseAccessRule("permit", "all")
+	c.Assert(err, NotNil)
+
+	c.Assert((&AccessRule{}).Match(net.ParseIP("127.0.0.1")), Equals, false)
+}
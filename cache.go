@@ -0,0 +1,284 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// CACHE_STATUS_ANY is status code used in cache validity table for "any"
+const CACHE_STATUS_ANY = 0
+
+// DEFAULT_PROXY_CACHE_KEY is default value of proxy_cache_key directive
+const DEFAULT_PROXY_CACHE_KEY = "$scheme$proxy_host$request_uri"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// CachePath contains info about proxy_cache_path directive
+type CachePath struct {
+	Path             string
+	Levels           []int
+	ZoneName         string
+	ZoneSize         Size
+	MaxSize          Size
+	MinFree          Size
+	Inactive         time.Duration
+	UseTempPath      bool
+	ManagerFiles     int
+	ManagerSleep     time.Duration
+	ManagerThreshold time.Duration
+	LoaderFiles      int
+	LoaderSleep      time.Duration
+	LoaderThreshold  time.Duration
+}
+
+// ProxyCache contains effective proxy cache configuration of location
+type ProxyCache struct {
+	ZoneName string
+	Zone     *CachePath
+	Key      string
+	Valid    map[int]time.Duration // Status code → cache time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetCachePaths returns proxy cache paths defined in HTTP block (zone name → path)
+func (h *HTTP) GetCachePaths() (map[string]*CachePath, error) {
+	if h == nil {
+		return nil, fmt.Errorf("HTTP is nil")
+	}
+
+	result := make(map[string]*CachePath)
+
+	for _, value := range h.Properties["proxy_cache_path"] {
+		path, err := ParseCachePath(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if result[path.ZoneName] != nil {
+			return nil, fmt.Errorf("Cache zone %s is already defined", path.ZoneName)
+		}
+
+		result[path.ZoneName] = path
+	}
+
+	return result, nil
+}
+
+// GetProxyCache returns effective proxy cache configuration of location (nil
+// if cache is disabled)
+func (l *Location) GetProxyCache() (*ProxyCache, error) {
+	if l == nil {
+		return nil, fmt.Errorf("Location is nil")
+	}
+
+	values := l.getInheritedValues("proxy_cache")
+
+	if len(values) == 0 || values[len(values)-1] == "off" {
+		return nil, nil
+	}
+
+	cache := &ProxyCache{
+		ZoneName: values[len(values)-1],
+		Key:      DEFAULT_PROXY_CACHE_KEY,
+		Valid:    make(map[int]time.Duration),
+	}
+
+	if keys := l.getInheritedValues("proxy_cache_key"); len(keys) != 0 {
+		cache.Key = unquote(keys[len(keys)-1])
+	}
+
+	for _, value := range l.getInheritedValues("proxy_cache_valid") {
+		codes, dur, err := ParseCacheValid(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, code := range codes {
+			cache.Valid[code] = dur
+		}
+	}
+
+	if l.Parent != nil && l.Parent.Parent != nil && !strings.Contains(cache.ZoneName, "$") {
+		paths, err := l.Parent.Parent.GetCachePaths()
+
+		if err != nil {
+			return nil, err
+		}
+
+		cache.Zone = paths[cache.ZoneName]
+	}
+
+	return cache, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseCachePath parses proxy_cache_path directive value
+func ParseCachePath(data string) (*CachePath, error) {
+	args := parseArgs(data)
+
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Invalid number of proxy_cache_path parameters")
+	}
+
+	path := &CachePath{
+		Path:             args[0],
+		Inactive:         10 * time.Minute,
+		UseTempPath:      true,
+		ManagerFiles:     100,
+		ManagerSleep:     50 * time.Millisecond,
+		ManagerThreshold: 200 * time.Millisecond,
+		LoaderFiles:      100,
+		LoaderSleep:      50 * time.Millisecond,
+		LoaderThreshold:  200 * time.Millisecond,
+	}
+
+	for _, arg := range args[1:] {
+		index := strings.IndexByte(arg, '=')
+
+		if index == -1 {
+			return nil, fmt.Errorf("Invalid proxy_cache_path parameter %s", arg)
+		}
+
+		err := path.setParameter(arg[:index], arg[index+1:])
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy_cache_path parameter %s: %v", arg, err)
+		}
+	}
+
+	if path.ZoneName == "" {
+		return nil, fmt.Errorf("Invalid proxy_cache_path %s: keys_zone is not set", path.Path)
+	}
+
+	return path, nil
+}
+
+// ParseCacheValid parses proxy_cache_valid directive value and returns status
+// codes and cache time
+func ParseCacheValid(data string) ([]int, time.Duration, error) {
+	var codes []int
+
+	args := parseArgs(data)
+
+	if len(args) == 0 {
+		return nil, 0, fmt.Errorf("Invalid number of proxy_cache_valid parameters")
+	}
+
+	dur, err := ParseTimeSec(args[len(args)-1])
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("Invalid proxy_cache_valid time: %v", err)
+	}
+
+	if len(args) == 1 {
+		return []int{200, 301, 302}, dur, nil
+	}
+
+	for _, arg := range args[:len(args)-1] {
+		if arg == "any" {
+			codes = append(codes, CACHE_STATUS_ANY)
+			continue
+		}
+
+		code, err := strconv.Atoi(arg)
+
+		if err != nil || code < 100 || code > 599 {
+			return nil, 0, fmt.Errorf("Invalid proxy_cache_valid status code %s", arg)
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, dur, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetValid returns cache time for response with given status code
+func (c *ProxyCache) GetValid(code int) time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	if dur, ok := c.Valid[code]; ok {
+		return dur
+	}
+
+	return c.Valid[CACHE_STATUS_ANY]
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// setParameter parses and sets proxy_cache_path parameter
+func (p *CachePath) setParameter(name, value string) error {
+	var err error
+
+	switch name {
+	case "levels":
+		p.Levels, err = parseCacheLevels(value)
+	case "keys_zone":
+		p.ZoneName, p.ZoneSize, err = parseZoneSpec(value)
+	case "max_size":
+		p.MaxSize, err = ParseSize(value)
+	case "min_free":
+		p.MinFree, err = ParseSize(value)
+	case "inactive":
+		p.Inactive, err = ParseTimeSec(value)
+	case "use_temp_path":
+		p.UseTempPath, err = parseBool(value)
+	case "manager_files":
+		p.ManagerFiles, err = parseLimitNumber(value)
+	case "manager_sleep":
+		p.ManagerSleep, err = ParseTimeMsec(value)
+	case "manager_threshold":
+		p.ManagerThreshold, err = ParseTimeMsec(value)
+	case "loader_files":
+		p.LoaderFiles, err = parseLimitNumber(value)
+	case "loader_sleep":
+		p.LoaderSleep, err = ParseTimeMsec(value)
+	case "loader_threshold":
+		p.LoaderThreshold, err = ParseTimeMsec(value)
+	case "purger", "purger_files", "purger_sleep", "purger_threshold":
+		// NGINX Plus only parameters
+	default:
+		return fmt.Errorf("Unsupported parameter")
+	}
+
+	return err
+}
+
+// parseCacheLevels parses cache levels (like 1:2)
+func parseCacheLevels(data string) ([]int, error) {
+	var result []int
+
+	parts := strings.Split(data, ":")
+
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("Too many levels")
+	}
+
+	for _, part := range parts {
+		if part != "1" && part != "2" {
+			return nil, fmt.Errorf("Invalid level %s", part)
+		}
+
+		result = append(result, int(part[0]-'0'))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestProxyCache(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	http := config.HTTP
+	http.Properties["proxy_cache_path"] = []string{
+		"/var/cache/webkaos/api levels=1:2 keys_zone=api:10m max_size=10g inactive=60m use_temp_path=off",
+		"/var/cache/webkaos/static keys_zone=static:1m manager_files=50 loader_sleep=100ms",
+	}
+	http.Properties["proxy_cache_valid"] = []string{"1h"}
+
+	paths, err := http.GetCachePaths()
+
+	c.Assert(err, IsNil)
+	c.Assert(paths, HasLen, 2)
+	c.Assert(paths["api"], DeepEquals, &CachePath{
+		Path: "/var/cache/webkaos/api", Levels: []int{1, 2},
+		ZoneName: "api", ZoneSize: 10 * MEGABYTE, MaxSize: 10 * GIGABYTE,
+		Inactive: time.Hour, UseTempPath: false,
+		ManagerFiles: 100, ManagerSleep: 50 * time.Millisecond, ManagerThreshold: 200 * time.Millisecond,
+		LoaderFiles: 100, LoaderSleep: 50 * time.Millisecond, LoaderThreshold: 200 * time.Millisecond,
+	})
+	c.Assert(paths["static"].ManagerFiles, Equals, 50)
+	c.Assert(paths["static"].LoaderSleep, Equals, 100*time.Millisecond)
+	c.Assert(paths["static"].Inactive, Equals, 10*time.Minute)
+
+	server := http.FindServer("service.domain.com", "https")
+	location := server.Locations[1]
+
+	cache, err := location.GetProxyCache()
+
+	c.Assert(err, IsNil)
+	c.Assert(cache, IsNil)
+
+	server.Properties.Data["proxy_cache"] = []ConditionalProperty{{-1, "api"}}
+	location.Properties.Data["proxy_cache_key"] = []ConditionalProperty{{-1, `"$host$request_uri"`}}
+	location.Properties.Data["proxy_cache_valid"] = []ConditionalProperty{
+		{-1, "200 302 10m"}, {-1, "404 1m"}, {-1, "any 5s"},
+	}
+
+	cache, err = location.GetProxyCache()
+
+	c.Assert(err, IsNil)
+	c.Assert(cache.ZoneName, Equals, "api")
+	c.Assert(cache.Zone, NotNil)
+	c.Assert(cache.Zone.Path, Equals, "/var/cache/webkaos/api")
+	c.Assert(cache.Key, Equals, "$host$request_uri")
+	c.Assert(cache.Valid, DeepEquals, map[int]time.Duration{
+		200: 10 * time.Minute, 302: 10 * time.Minute, 404: time.Minute, CACHE_STATUS_ANY: 5 * time.Second,
+	})
+	c.Assert(cache.GetValid(302), Equals, 10*time.Minute)
+	c.Assert(cache.GetValid(500), Equals, 5*time.Second)
+
+	location.Properties.Data["proxy_cache"] = []ConditionalProperty{{-1, "unknown"}}
+	delete(location.Properties.Data, "proxy_cache_key")
+	delete(location.Properties.Data, "proxy_cache_valid")
+
+	cache, err = location.GetProxyCache()
+
+	c.Assert(err, IsNil)
+	c.Assert(cache.Zone, IsNil)
+	c.Assert(cache.Key, Equals, DEFAULT_PROXY_CACHE_KEY)
+	c.Assert(cache.Valid, DeepEquals, map[int]time.Duration{200: time.Hour, 301: time.Hour, 302: time.Hour})
+
+	location.Properties.Data["proxy_cache"] = []ConditionalProperty{{-1, "off"}}
+
+	cache, err = location.GetProxyCache()
+
+	c.Assert(err, IsNil)
+	c.Assert(cache, IsNil)
+	c.Assert(cache.GetValid(200), Equals, time.Duration(0))
+
+	location.Properties.Data["proxy_cache"] = []ConditionalProperty{{-1, "api"}}
+	location.Properties.Data["proxy_cache_valid"] = []ConditionalProperty{{-1, "200 1x"}}
+
+	_, err = location.GetProxyCache()
+	c.Assert(err, NotNil)
+
+	delete(location.Properties.Data, "proxy_cache_valid")
+	http.Properties["proxy_cache_path"] = append(http.Properties["proxy_cache_path"], "/tmp keys_zone=api:1m")
+
+	_, err = location.GetProxyCache()
+	c.Assert(err, NotNil)
+	_, err = http.GetCachePaths()
+	c.Assert(err, NotNil)
+
+	http.Properties["proxy_cache_path"] = []string{"/tmp levels=3"}
+
+	_, err = http.GetCachePaths()
+	c.Assert(err, NotNil)
+
+	for _, data := range []string{
+		"/tmp", "/tmp levels=1:2", "/tmp keys_zone=a:1m levels=1:2:2:2",
+		"/tmp keys_zone=a:1m fast", "/tmp keys_zone=a:1m speed=1",
+		"/tmp keys_zone=a:1m inactive=1ms", "/tmp keys_zone=a",
+	} {
+		_, err = ParseCachePath(data)
+		c.Assert(err, NotNil, Commentf("Path: %s", data))
+	}
+
+	for _, data := range []string{"", "abc 1m", "99 1m", "200 1ms"} {
+		_, _, err = ParseCacheValid(data)
+		c.Assert(err, NotNil, Commentf("Valid: %s", data))
+	}
+
+	_, err = (*Location)(nil).GetProxyCache()
+	c.Assert(err, NotNil)
+	_, err = (*HTTP)(nil).GetCachePaths()
+	c.Assert(err, NotNil)
+}
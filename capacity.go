@@ -0,0 +1,129 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	DEFAULT_WORKER_PROCESSES   = 1
+	DEFAULT_WORKER_CONNECTIONS = 512
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Capacity contains info about workers and connections capacity
+type Capacity struct {
+	WorkerProcesses   int
+	WorkerConnections int
+	RlimitNofile      int // Zero if worker_rlimit_nofile is not set
+	MaxClients        int // Max number of clients for static content
+	MaxProxiedClients int // Max number of clients for proxied requests
+	Warnings          []string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetCapacity calculates workers and connections capacity. CPU count is used
+// for resolving "auto" value of worker_processes.
+func (c *Config) GetCapacity(cpus int) (*Capacity, error) {
+	var err error
+
+	if c == nil {
+		return nil, fmt.Errorf("Config is nil")
+	}
+
+	capacity := &Capacity{
+		WorkerProcesses:   DEFAULT_WORKER_PROCESSES,
+		WorkerConnections: DEFAULT_WORKER_CONNECTIONS,
+	}
+
+	if value := c.Core.Get("worker_processes"); value != "" {
+		capacity.WorkerProcesses, err = parseWorkerProcesses(value, cpus)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if value := c.Core.Get("worker_rlimit_nofile"); value != "" {
+		capacity.RlimitNofile, err = parseLimitNumber(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid worker_rlimit_nofile value %s", value)
+		}
+	}
+
+	if value := c.Events.Get("worker_connections"); value != "" {
+		capacity.WorkerConnections, err = parseLimitNumber(value)
+
+		if err != nil || capacity.WorkerConnections == 0 {
+			return nil, fmt.Errorf("Invalid worker_connections value %s", value)
+		}
+	}
+
+	capacity.MaxClients = capacity.WorkerProcesses * capacity.WorkerConnections
+	// Every proxied request uses two connections (client and upstream)
+	capacity.MaxProxiedClients = capacity.MaxClients / 2
+	capacity.Warnings = capacity.check()
+
+	return capacity, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// check checks capacity values and returns warnings
+func (c *Capacity) check() []string {
+	var result []string
+
+	switch {
+	case c.RlimitNofile == 0:
+		result = append(result, fmt.Sprintf(
+			"worker_rlimit_nofile is not set, system limit must be at least %d",
+			c.WorkerConnections*2,
+		))
+
+	case c.RlimitNofile < c.WorkerConnections:
+		result = append(result, fmt.Sprintf(
+			"worker_connections (%d) exceed worker_rlimit_nofile (%d)",
+			c.WorkerConnections, c.RlimitNofile,
+		))
+
+	case c.RlimitNofile < c.WorkerConnections*2:
+		result = append(result, fmt.Sprintf(
+			"worker_rlimit_nofile (%d) is less than worker_connections × 2 (%d)",
+			c.RlimitNofile, c.WorkerConnections*2,
+		))
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseWorkerProcesses parses worker_processes value
+func parseWorkerProcesses(data string, cpus int) (int, error) {
+	if data == "auto" {
+		if cpus < 1 {
+			return 0, fmt.Errorf("Can't resolve worker_processes auto: invalid CPU count %d", cpus)
+		}
+
+		return cpus, nil
+	}
+
+	num, err := parseLimitNumber(data)
+
+	if err != nil || num == 0 {
+		return 0, fmt.Errorf("Invalid worker_processes value %s", data)
+	}
+
+	return num, nil
+}
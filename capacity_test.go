@@ -0,0 +1,85 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestCapacity(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	capacity, err := config.GetCapacity(4)
+
+	c.Assert(err, IsNil)
+	c.Assert(capacity, DeepEquals, &Capacity{
+		WorkerProcesses:   4,
+		WorkerConnections: 8192,
+		RlimitNofile:      65536,
+		MaxClients:        32768,
+		MaxProxiedClients: 16384,
+	})
+
+	_, err = config.GetCapacity(0)
+	c.Assert(err, NotNil)
+
+	config.Core["worker_processes"] = []string{"2"}
+	config.Core["worker_rlimit_nofile"] = []string{"10000"}
+
+	capacity, err = config.GetCapacity(0)
+
+	c.Assert(err, IsNil)
+	c.Assert(capacity.MaxClients, Equals, 16384)
+	c.Assert(capacity.Warnings, DeepEquals, []string{
+		"worker_rlimit_nofile (10000) is less than worker_connections × 2 (16384)",
+	})
+
+	config.Core["worker_rlimit_nofile"] = []string{"4096"}
+
+	capacity, err = config.GetCapacity(0)
+
+	c.Assert(err, IsNil)
+	c.Assert(capacity.Warnings, DeepEquals, []string{
+		"worker_connections (8192) exceed worker_rlimit_nofile (4096)",
+	})
+
+	config = &Config{Core: Properties{}}
+
+	capacity, err = config.GetCapacity(8)
+
+	c.Assert(err, IsNil)
+	c.Assert(capacity.WorkerProcesses, Equals, DEFAULT_WORKER_PROCESSES)
+	c.Assert(capacity.WorkerConnections, Equals, DEFAULT_WORKER_CONNECTIONS)
+	c.Assert(capacity.MaxProxiedClients, Equals, 256)
+	c.Assert(capacity.Warnings, DeepEquals, []string{
+		"worker_rlimit_nofile is not set, system limit must be at least 1024",
+	})
+
+	for _, props := range []Properties{
+		{"worker_processes": {"0"}},
+		{"worker_processes": {"many"}},
+		{"worker_rlimit_nofile": {"-1"}},
+	} {
+		config.Core = props
+		_, err = config.GetCapacity(8)
+		c.Assert(err, NotNil, Commentf("Core: %v", props))
+	}
+
+	config.Core = nil
+	config.Events = Properties{"worker_connections": {"0"}}
+
+	_, err = config.GetCapacity(8)
+	c.Assert(err, NotNil)
+
+	_, err = (*Config)(nil).GetCapacity(8)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,240 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// CertificateReport contains info about certificate used by server
+type CertificateReport struct {
+	Context     string
+	Server      *Server
+	Certificate *SSLCertificate
+	Subject     string
+	Names       []string
+	NotAfter    time.Time
+	ChainSize   int // Number of certificates in file (including leaf)
+	Problems    []string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AuditCertificates checks certificates of all servers with SSL (relative paths
+// are resolved from configuration root). Certificates which expire in given
+// period are reported as expiring.
+func (c *Config) AuditCertificates(now time.Time, period time.Duration) ([]*CertificateReport, error) {
+	var result []*CertificateReport
+
+	if c == nil || c.HTTP == nil {
+		return nil, fmt.Errorf("Config doesn't contain HTTP block")
+	}
+
+	for index, server := range c.HTTP.Servers {
+		ssl, err := server.GetSSL()
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", getServerContext(index), err)
+		}
+
+		if !ssl.Enabled {
+			continue
+		}
+
+		for _, cert := range ssl.Certificates {
+			report := &CertificateReport{
+				Context:     getServerContext(index),
+				Server:      server,
+				Certificate: cert,
+			}
+
+			report.check(c.Root, now, period)
+			result = append(result, report)
+		}
+	}
+
+	return result, nil
+}
+
+// IsOK returns true if there are no problems with certificate
+func (r *CertificateReport) IsOK() bool {
+	return r != nil && len(r.Problems) == 0
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// check reads certificate and key and checks them
+func (r *CertificateReport) check(root string, now time.Time, period time.Duration) {
+	if strings.Contains(r.Certificate.Certificate+r.Certificate.Key, "$") {
+		r.addProblem("Certificate path contains variables and can't be checked")
+		return
+	}
+
+	certData, err := ioutil.ReadFile(getAbsPath(root, r.Certificate.Certificate))
+
+	if err != nil {
+		r.addProblem("Can't read certificate: %v", err)
+		return
+	}
+
+	chain, err := parseCertificateChain(certData)
+
+	if err != nil {
+		r.addProblem("Can't parse certificate: %v", err)
+		return
+	}
+
+	leaf := chain[0]
+
+	r.Subject = leaf.Subject.String()
+	r.Names = leaf.DNSNames
+	r.NotAfter = leaf.NotAfter
+	r.ChainSize = len(chain)
+
+	r.checkKey(root, certData)
+	r.checkNames(leaf)
+	r.checkChain(chain)
+
+	switch {
+	case now.After(leaf.NotAfter):
+		r.addProblem("Certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+	case now.Add(period).After(leaf.NotAfter):
+		r.addProblem("Certificate expires at %s", leaf.NotAfter.Format(time.RFC3339))
+	case now.Before(leaf.NotBefore):
+		r.addProblem("Certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	}
+}
+
+// checkKey checks that private key matches certificate
+func (r *CertificateReport) checkKey(root string, certData []byte) {
+	keyData, err := ioutil.ReadFile(getAbsPath(root, r.Certificate.Key))
+
+	if err != nil {
+		r.addProblem("Can't read private key: %v", err)
+		return
+	}
+
+	_, err = tls.X509KeyPair(certData, keyData)
+
+	if err != nil {
+		r.addProblem("Private key doesn't match certificate: %v", err)
+	}
+}
+
+// checkNames checks that certificate covers all server names
+func (r *CertificateReport) checkNames(leaf *x509.Certificate) {
+	for _, name := range r.Server.GetNames() {
+		var names []string
+
+		switch {
+		case name == "_", name == "", strings.HasPrefix(name, "~"),
+			strings.HasSuffix(name, ".*"):
+			continue
+		case strings.HasPrefix(name, "."):
+			names = []string{name[1:], "*" + name}
+		default:
+			names = []string{name}
+		}
+
+		for _, n := range names {
+			if !isNameCovered(leaf, n) {
+				r.addProblem("Certificate doesn't cover server name %s", n)
+			}
+		}
+	}
+}
+
+// checkChain checks certificate chain
+func (r *CertificateReport) checkChain(chain []*x509.Certificate) {
+	leaf := chain[0]
+
+	if len(chain) == 1 && leaf.Issuer.String() != leaf.Subject.String() {
+		r.addProblem("Chain certificates are missing")
+		return
+	}
+
+	for i := 0; i < len(chain)-1; i++ {
+		if chain[i].CheckSignatureFrom(chain[i+1]) != nil {
+			r.addProblem("Certificate %d in chain isn't signed by certificate %d", i, i+1)
+		}
+	}
+}
+
+// addProblem adds problem to report
+func (r *CertificateReport) addProblem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseCertificateChain parses all certificates in PEM data
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var result []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, cert)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("There are no PEM encoded certificates")
+	}
+
+	return result, nil
+}
+
+// isNameCovered returns true if certificate is valid for given name (wildcard
+// names must be presented in certificate as is)
+func isNameCovered(cert *x509.Certificate, name string) bool {
+	if !strings.HasPrefix(name, "*.") {
+		return cert.VerifyHostname(name) == nil
+	}
+
+	for _, certName := range cert.DNSNames {
+		if strings.EqualFold(certName, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getAbsPath returns absolute path for given path
+func getAbsPath(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(root, path)
+}
@@ -0,0 +1,174 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestCertificateAudit(c *C) {
+	dir := c.MkDir()
+	now := time.Now()
+
+	caKey, caCert := genTestCertificate(c, nil, nil, "Test CA", nil, now.Add(time.Hour*24*365))
+	leafKey, leafCert := genTestCertificate(c, caKey, caCert, "service.domain.com",
+		[]string{"service.domain.com", "*.domain.com"}, now.Add(time.Hour*24*10),
+	)
+	otherKey, _ := genTestCertificate(c, nil, nil, "other", nil, now.Add(time.Hour))
+	_, expiredCert := genTestCertificate(c, caKey, caCert, "old.domain.com",
+		[]string{"old.domain.com"}, now.Add(-time.Hour),
+	)
+
+	writeTestPEM(c, dir+"/chain.crt", leafCert, caCert)
+	writeTestPEM(c, dir+"/leaf.crt", leafCert)
+	writeTestPEM(c, dir+"/expired.crt", expiredCert)
+	writeTestPEM(c, dir+"/broken.crt", caCert, leafCert)
+	writeTestPEM(c, dir+"/leaf.key", leafKey)
+	writeTestPEM(c, dir+"/other.key", otherKey)
+	c.Assert(ioutil.WriteFile(dir+"/empty.crt", []byte("test"), 0644), IsNil)
+
+	newServer := func(names, cert, key string) *Server {
+		return &Server{
+			Properties: &ConditionalProperties{
+				Conditions: []string{},
+				Data: map[string][]ConditionalProperty{
+					"listen":              {{-1, "443 ssl"}},
+					"server_name":         {{-1, names}},
+					"ssl_certificate":     {{-1, cert}},
+					"ssl_certificate_key": {{-1, key}},
+				},
+			},
+		}
+	}
+
+	config := &Config{Root: dir, HTTP: &HTTP{Servers: []*Server{
+		newServer("service.domain.com .domain.com", "chain.crt", dir+"/leaf.key"),
+		newServer("service.domain.com", "leaf.crt", "other.key"),
+		newServer("_", "expired.crt", "unknown.key"),
+		newServer("www.example.com mail.* ~^www", "broken.crt", "leaf.key"),
+		newServer("_", "empty.crt", "leaf.key"),
+		newServer("_", "unknown.crt", "leaf.key"),
+		newServer("_", "$ssl_server_name.crt", "leaf.key"),
+		{Properties: &ConditionalProperties{Data: map[string][]ConditionalProperty{}}},
+	}}}
+
+	reports, err := config.AuditCertificates(now, time.Hour*24*30)
+
+	c.Assert(err, IsNil)
+	c.Assert(reports, HasLen, 7)
+
+	c.Assert(reports[0].Context, Equals, "http/server[0]")
+	c.Assert(reports[0].Subject, Equals, "CN=service.domain.com")
+	c.Assert(reports[0].Names, DeepEquals, []string{"service.domain.com", "*.domain.com"})
+	c.Assert(reports[0].ChainSize, Equals, 2)
+	c.Assert(reports[0].Problems, DeepEquals, []string{
+		"Certificate doesn't cover server name domain.com",
+		"Certificate expires at " + reports[0].NotAfter.Format(time.RFC3339),
+	})
+
+	reports, err = config.AuditCertificates(now, 0)
+
+	c.Assert(err, IsNil)
+	c.Assert(reports[0].Problems, HasLen, 1)
+	c.Assert(reports[1].Problems, HasLen, 2)
+	c.Assert(reports[1].Problems[0], Matches, "Private key doesn't match certificate: .*")
+	c.Assert(reports[1].Problems[1], Equals, "Chain certificates are missing")
+	c.Assert(reports[2].Problems, HasLen, 3)
+	c.Assert(reports[2].Problems[0], Matches, "Can't read private key: .*")
+	c.Assert(reports[2].Problems[2], Matches, "Certificate expired at .*")
+	c.Assert(reports[3].Problems, DeepEquals, []string{
+		"Private key doesn't match certificate: tls: private key does not match public key",
+		"Certificate doesn't cover server name www.example.com",
+		"Certificate 0 in chain isn't signed by certificate 1",
+	})
+	c.Assert(reports[4].Problems[0], Matches, "Can't parse certificate: .*")
+	c.Assert(reports[5].Problems[0], Matches, "Can't read certificate: .*")
+	c.Assert(reports[6].Problems[0], Equals, "Certificate path contains variables and can't be checked")
+	c.Assert(reports[6].IsOK(), Equals, false)
+
+	config.HTTP.Servers = config.HTTP.Servers[:1]
+	config.HTTP.Servers[0].Properties.Data["server_name"] = []ConditionalProperty{{-1, "service.domain.com"}}
+
+	reports, err = config.AuditCertificates(now, 0)
+
+	c.Assert(err, IsNil)
+	c.Assert(reports[0].IsOK(), Equals, true)
+
+	config.HTTP.Servers[0].Properties.Data["ssl_certificate"] = nil
+
+	_, err = config.AuditCertificates(now, 0)
+	c.Assert(err, NotNil)
+
+	_, err = (&Config{}).AuditCertificates(now, 0)
+	c.Assert(err, NotNil)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// genTestCertificate generates certificate signed by given CA (or self-signed
+// CA certificate if parent is nil)
+func genTestCertificate(c *C, parentKey *ecdsa.PrivateKey, parent *x509.Certificate, cn string, names []string, notAfter time.Time) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	c.Assert(err, IsNil)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     names,
+		NotBefore:    notAfter.Add(-time.Hour * 24 * 400),
+		NotAfter:     notAfter,
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+
+	c.Assert(err, IsNil)
+
+	cert, err := x509.ParseCertificate(data)
+
+	c.Assert(err, IsNil)
+
+	return key, cert
+}
+
+// writeTestPEM writes certificates or keys to PEM file
+func writeTestPEM(c *C, file string, items ...interface{}) {
+	var data []byte
+
+	for _, item := range items {
+		switch v := item.(type) {
+		case *x509.Certificate:
+			data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: v.Raw})...)
+		case *ecdsa.PrivateKey:
+			keyData, err := x509.MarshalECPrivateKey(v)
+			c.Assert(err, IsNil)
+			data = append(data, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyData})...)
+		}
+	}
+
+	c.Assert(ioutil.WriteFile(file, data, 0644), IsNil)
+}
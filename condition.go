@@ -0,0 +1,275 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	OP_NONE            = ""
+	OP_EQUAL           = "="
+	OP_NOT_EQUAL       = "!="
+	OP_MATCH           = "~"
+	OP_MATCH_ICASE     = "~*"
+	OP_NOT_MATCH       = "!~"
+	OP_NOT_MATCH_ICASE = "!~*"
+	OP_FILE            = "-f"
+	OP_NOT_FILE        = "!-f"
+	OP_DIR             = "-d"
+	OP_NOT_DIR         = "!-d"
+	OP_EXISTS          = "-e"
+	OP_NOT_EXISTS      = "!-e"
+	OP_EXEC            = "-x"
+	OP_NOT_EXEC        = "!-x"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Condition contains parsed "if" condition
+type Condition struct {
+	Variable string // Variable (or path for file checks)
+	Operator string
+	Operand  string
+
+	regex *regexp.Regexp
+}
+
+// FileSystem is interface for file system used for file checks in conditions
+type FileSystem interface {
+	Stat(path string) (os.FileInfo, error)
+}
+
+// StubFileSystem is file system stub (path → file mode)
+type StubFileSystem map[string]os.FileMode
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// osFileSystem is file system which uses OS calls
+type osFileSystem struct{}
+
+// stubFileInfo is os.FileInfo implementation for file system stub
+type stubFileInfo struct {
+	name string
+	mode os.FileMode
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseCondition parses "if" condition
+func ParseCondition(data string) (*Condition, error) {
+	args := parseArgs(data)
+
+	switch len(args) {
+	case 1:
+		if !strings.HasPrefix(args[0], "$") {
+			return nil, fmt.Errorf("Invalid condition %q: variable expected", data)
+		}
+
+		return &Condition{Variable: args[0]}, nil
+
+	case 2:
+		switch args[0] {
+		case OP_FILE, OP_NOT_FILE, OP_DIR, OP_NOT_DIR,
+			OP_EXISTS, OP_NOT_EXISTS, OP_EXEC, OP_NOT_EXEC:
+			return &Condition{Variable: args[1], Operator: args[0]}, nil
+		}
+
+		return nil, fmt.Errorf("Invalid condition %q: unsupported file check %s", data, args[0])
+
+	case 3:
+		if !strings.HasPrefix(args[0], "$") {
+			return nil, fmt.Errorf("Invalid condition %q: variable expected", data)
+		}
+
+		cond := &Condition{Variable: args[0], Operator: args[1], Operand: args[2]}
+
+		switch cond.Operator {
+		case OP_EQUAL, OP_NOT_EQUAL:
+			return cond, nil
+
+		case OP_MATCH, OP_MATCH_ICASE, OP_NOT_MATCH, OP_NOT_MATCH_ICASE:
+			var err error
+
+			cond.regex, err = compileRegexp(cond.Operand, strings.HasSuffix(cond.Operator, "*"))
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid condition %q: %v", data, err)
+			}
+
+			return cond, nil
+		}
+
+		return nil, fmt.Errorf("Invalid condition %q: unsupported operator %s", data, cond.Operator)
+	}
+
+	return nil, fmt.Errorf("Invalid condition %q", data)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Evaluate evaluates condition using given variables (name without "$" → value)
+// and file system (if file system is nil, OS file system will be used)
+func (c *Condition) Evaluate(vars map[string]string, fs FileSystem) (bool, error) {
+	result, _, err := c.evaluate(vars, fs)
+	return result, err
+}
+
+// IsNegative returns true if condition operator is negative
+func (c *Condition) IsNegative() bool {
+	return c != nil && strings.HasPrefix(c.Operator, "!")
+}
+
+// String returns condition as a string
+func (c *Condition) String() string {
+	if c == nil {
+		return ""
+	}
+
+	switch {
+	case c.Operator == OP_NONE:
+		return c.Variable
+	case c.Operand == "":
+		return c.Operator + " " + c.Variable
+	}
+
+	return c.Variable + " " + c.Operator + " " + c.Operand
+}
+
+// evaluate evaluates condition and returns regex captures (if regex matched)
+func (c *Condition) evaluate(vars map[string]string, fs FileSystem) (bool, []string, error) {
+	if c == nil {
+		return false, nil, fmt.Errorf("Condition is nil")
+	}
+
+	value := expandVariables(c.Variable, vars)
+
+	switch c.Operator {
+	case OP_NONE:
+		return value != "" && value != "0", nil, nil
+
+	case OP_EQUAL, OP_NOT_EQUAL:
+		equal := value == expandVariables(c.Operand, vars)
+		return equal == (c.Operator == OP_EQUAL), nil, nil
+
+	case OP_MATCH, OP_MATCH_ICASE:
+		groups := c.regex.FindStringSubmatch(value)
+		return groups != nil, groups, nil
+
+	case OP_NOT_MATCH, OP_NOT_MATCH_ICASE:
+		return !c.regex.MatchString(value), nil, nil
+	}
+
+	if fs == nil {
+		fs = osFileSystem{}
+	}
+
+	info, err := fs.Stat(value)
+	exists := err == nil
+
+	var result bool
+
+	switch strings.TrimPrefix(c.Operator, "!") {
+	case OP_FILE:
+		result = exists && info.Mode().IsRegular()
+	case OP_DIR:
+		result = exists && info.IsDir()
+	case OP_EXISTS:
+		result = exists
+	case OP_EXEC:
+		result = exists && info.Mode()&0100 != 0
+	default:
+		return false, nil, fmt.Errorf("Unsupported operator %s", c.Operator)
+	}
+
+	return result != c.IsNegative(), nil, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetCondition parses and returns condition with given ID
+func (p *ConditionalProperties) GetCondition(id int) (*Condition, error) {
+	if p == nil || id < 0 || id >= len(p.Conditions) {
+		return nil, fmt.Errorf("There is no condition with ID %d", id)
+	}
+
+	return ParseCondition(p.Conditions[id])
+}
+
+// CheckCondition evaluates condition with given ID (properties without
+// condition have ID -1 and always apply)
+func (p *ConditionalProperties) CheckCondition(id int, vars map[string]string, fs FileSystem) (bool, error) {
+	if id == -1 {
+		return true, nil
+	}
+
+	cond, err := p.GetCondition(id)
+
+	if err != nil {
+		return false, err
+	}
+
+	return cond.Evaluate(vars, fs)
+}
+
+// GetApplicable returns values of property with given name which apply for
+// given variables and file system
+func (p *ConditionalProperties) GetApplicable(name string, vars map[string]string, fs FileSystem) ([]string, error) {
+	var result []string
+
+	if p == nil || p.Data == nil {
+		return nil, nil
+	}
+
+	for _, prop := range p.Data[name] {
+		ok, err := p.CheckCondition(prop.ConditionID, vars, fs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			result = append(result, prop.Value)
+		}
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Stat returns info about file
+func (fs osFileSystem) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+// Stat returns info about file from stub
+func (fs StubFileSystem) Stat(path string) (os.FileInfo, error) {
+	mode, ok := fs[path]
+
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+
+	return stubFileInfo{path, mode}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (i stubFileInfo) Name() string       { return i.name }
+func (i stubFileInfo) Size() int64        { return 0 }
+func (i stubFileInfo) Mode() os.FileMode  { return i.mode }
+func (i stubFileInfo) ModTime() time.Time { return time.Time{} }
+func (i stubFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i stubFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,173 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"os"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestConditionParsing(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	server := config.HTTP.FindServer("service.domain.com", "https")
+
+	c.Assert(server.Properties.Conditions, DeepEquals, []string{"$http_user_agent ~* (client1)"})
+
+	cond, err := server.Properties.GetCondition(0)
+
+	c.Assert(err, IsNil)
+	c.Assert(cond.Variable, Equals, "$http_user_agent")
+	c.Assert(cond.Operator, Equals, OP_MATCH_ICASE)
+	c.Assert(cond.Operand, Equals, "(client1)")
+	c.Assert(cond.String(), Equals, "$http_user_agent ~* (client1)")
+
+	_, err = server.Properties.GetCondition(1)
+	c.Assert(err, NotNil)
+
+	vars := map[string]string{"http_user_agent": "Client1/1.0"}
+
+	values, err := server.Properties.GetApplicable("return", vars, nil)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"403"})
+
+	vars["http_user_agent"] = "curl/7.29.0"
+
+	values, err = server.Properties.GetApplicable("return", vars, nil)
+	c.Assert(err, IsNil)
+	c.Assert(values, IsNil)
+
+	values, err = server.Properties.GetApplicable("ssl_certificate", vars, nil)
+	c.Assert(err, IsNil)
+	c.Assert(values, DeepEquals, []string{"/etc/webkaos/ssl/my-chain.crt"})
+
+	props := &ConditionalProperties{
+		Conditions: []string{"$k == 1"},
+		Data:       map[string][]ConditionalProperty{"return": {{0, "403"}}},
+	}
+
+	_, err = props.GetApplicable("return", vars, nil)
+	c.Assert(err, NotNil)
+
+	var nilProps *ConditionalProperties
+	values, err = nilProps.GetApplicable("return", vars, nil)
+	c.Assert(err, IsNil)
+	c.Assert(values, IsNil)
+
+	c.Assert(getCondition("if $a {"), Equals, "")
+	c.Assert(getCondition("if ( $a = 1 ) {"), Equals, "$a = 1")
+}
+
+func (s *NginxSuite) TestConditionEvaluation(c *C) {
+	vars := map[string]string{
+		"request_uri":      "/Static/image.png",
+		"request_filename": "/srv/www/index.html",
+		"document_root":    "/srv/www",
+		"host":             "domain.com",
+		"zero":             "0",
+		"one":              "1",
+	}
+
+	fs := StubFileSystem{
+		"/srv/www":            os.ModeDir | 0755,
+		"/srv/www/index.html": 0644,
+		"/srv/www/run.sh":     0755,
+	}
+
+	conditions := map[string]bool{
+		"$one":                          true,
+		"$zero":                         false,
+		"$unknown":                      false,
+		"$host = domain.com":            true,
+		"$host = 'domain.com'":          true,
+		"$host != domain.com":           false,
+		"$host = $host":                 true,
+		"$request_uri ~ ^/static/":      false,
+		"$request_uri ~* ^/static/":     true,
+		"$request_uri !~ ^/static/":     true,
+		"$request_uri !~* ^/static/":    false,
+		"$request_uri ~ \"\\.png$\"":    true,
+		"-f $request_filename":          true,
+		"!-f $request_filename":         false,
+		"-f $document_root":             false,
+		"-d $document_root":             true,
+		"!-d $document_root":            false,
+		"-e $document_root/index.html":  true,
+		"!-e $document_root/index.html": false,
+		"-e $document_root/unknown":     false,
+		"!-e $document_root/unknown":    true,
+		"-x $document_root/run.sh":      true,
+		"-x $request_filename":          false,
+		"!-x $request_filename":         true,
+	}
+
+	for data, expected := range conditions {
+		cond, err := ParseCondition(data)
+		c.Assert(err, IsNil, Commentf("Condition: %s", data))
+
+		result, err := cond.Evaluate(vars, fs)
+		c.Assert(err, IsNil, Commentf("Condition: %s", data))
+		c.Assert(result, Equals, expected, Commentf("Condition: %s", data))
+	}
+
+	cond, _ := ParseCondition("-d /")
+	result, err := cond.Evaluate(nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(result, Equals, true)
+	c.Assert(cond.String(), Equals, "-d /")
+	c.Assert(cond.IsNegative(), Equals, false)
+
+	cond, _ = ParseCondition("!-e $unknown")
+	c.Assert(cond.IsNegative(), Equals, true)
+
+	props := &ConditionalProperties{Conditions: []string{"$one"}}
+
+	ok, err := props.CheckCondition(-1, vars, fs)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	ok, err = props.CheckCondition(0, vars, fs)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	_, err = props.CheckCondition(1, vars, fs)
+	c.Assert(err, NotNil)
+
+	var nilCond *Condition
+	_, err = nilCond.Evaluate(vars, fs)
+	c.Assert(err, NotNil)
+	c.Assert(nilCond.String(), Equals, "")
+	c.Assert(nilCond.IsNegative(), Equals, false)
+
+	cond = &Condition{Variable: "/", Operator: "-z"}
+	_, err = cond.Evaluate(vars, fs)
+	c.Assert(err, NotNil)
+
+	info, _ := fs.Stat("/srv/www")
+	c.Assert(info.Name(), Equals, "/srv/www")
+	c.Assert(info.Size(), Equals, int64(0))
+	c.Assert(info.ModTime().IsZero(), Equals, true)
+	c.Assert(info.Sys(), IsNil)
+}
+
+func (s *NginxSuite) TestConditionErrors(c *C) {
+	badConditions := []string{
+		"", "host", "-z $host", "$k == 1", "host = 1", "$host ~ (abc",
+		"$a = 1 2",
+	}
+
+	for _, data := range badConditions {
+		_, err := ParseCondition(data)
+		c.Assert(err, NotNil, Commentf("Condition: %s", data))
+	}
+}
@@ -0,0 +1,360 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	LOG_LEVEL_DEBUG  = "debug"
+	LOG_LEVEL_INFO   = "info"
+	LOG_LEVEL_NOTICE = "notice"
+	LOG_LEVEL_WARN   = "warn"
+	LOG_LEVEL_ERROR  = "error"
+	LOG_LEVEL_CRIT   = "crit"
+	LOG_LEVEL_ALERT  = "alert"
+	LOG_LEVEL_EMERG  = "emerg"
+)
+
+// DEFAULT_THREAD_POOL_QUEUE is default max size of thread pool tasks queue
+const DEFAULT_THREAD_POOL_QUEUE = 65536
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Core contains info about main context directives
+type Core struct {
+	User                  string
+	Group                 string
+	WorkerProcesses       int // Zero if worker_processes is set to "auto"
+	WorkerCPUAffinity     []string
+	WorkerPriority        int
+	WorkerRlimitNofile    int
+	WorkerRlimitCore      Size
+	WorkerShutdownTimeout time.Duration
+	WorkingDirectory      string
+	PID                   string
+	ErrorLogs             []*ErrorLog
+	Modules               []string
+	Env                   map[string]string // Variable → value ("" for inherited variables)
+	ThreadPools           map[string]*ThreadPool
+	PCREJIT               bool
+}
+
+// Events contains info about events block directives
+type Events struct {
+	Use               string
+	WorkerConnections int
+	MultiAccept       bool
+	AcceptMutex       bool
+	AcceptMutexDelay  time.Duration
+}
+
+// ErrorLog contains info about error_log directive
+type ErrorLog struct {
+	Target string
+	Level  string
+	Syslog *Syslog
+}
+
+// ThreadPool contains info about thread_pool directive
+type ThreadPool struct {
+	Name     string
+	Threads  int
+	MaxQueue int
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// logLevels contains error log levels sorted by severity
+var logLevels = []string{
+	LOG_LEVEL_DEBUG, LOG_LEVEL_INFO, LOG_LEVEL_NOTICE, LOG_LEVEL_WARN,
+	LOG_LEVEL_ERROR, LOG_LEVEL_CRIT, LOG_LEVEL_ALERT, LOG_LEVEL_EMERG,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetCore returns typed info about main context directives
+func (c *Config) GetCore() (*Core, error) {
+	var err error
+
+	if c == nil {
+		return nil, fmt.Errorf("Config is nil")
+	}
+
+	core := &Core{
+		WorkerProcesses:   DEFAULT_WORKER_PROCESSES,
+		WorkerCPUAffinity: strings.Fields(c.Core.Get("worker_cpu_affinity")),
+		WorkingDirectory:  c.Core.Get("working_directory"),
+		PID:               c.Core.Get("pid"),
+		Modules:           c.Core["load_module"],
+		Env:               make(map[string]string),
+		ThreadPools:       make(map[string]*ThreadPool),
+	}
+
+	if value := c.Core.Get("user"); value != "" {
+		args := parseArgs(value)
+
+		if len(args) > 2 {
+			return nil, fmt.Errorf("Invalid user value %s", value)
+		}
+
+		core.User, core.Group = args[0], args[0]
+
+		if len(args) == 2 {
+			core.Group = args[1]
+		}
+	}
+
+	switch value := c.Core.Get("worker_processes"); value {
+	case "":
+		// use default value
+	case "auto":
+		core.WorkerProcesses = 0
+	default:
+		core.WorkerProcesses, err = parseWorkerProcesses(value, 0)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if value := c.Core.Get("worker_priority"); value != "" {
+		core.WorkerPriority, err = strconv.Atoi(value)
+
+		if err != nil || core.WorkerPriority < -20 || core.WorkerPriority > 20 {
+			return nil, fmt.Errorf("Invalid worker_priority value %s", value)
+		}
+	}
+
+	if value := c.Core.Get("worker_rlimit_nofile"); value != "" {
+		core.WorkerRlimitNofile, err = parseLimitNumber(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid worker_rlimit_nofile value %s", value)
+		}
+	}
+
+	if c.Core.Get("worker_rlimit_core") != "" {
+		core.WorkerRlimitCore, err = c.Core.GetSize("worker_rlimit_core")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Core.Get("worker_shutdown_timeout") != "" {
+		core.WorkerShutdownTimeout, err = c.Core.GetTime("worker_shutdown_timeout")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Core.Get("pcre_jit") != "" {
+		core.PCREJIT, err = c.Core.GetBool("pcre_jit")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, value := range c.Core["error_log"] {
+		errorLog, err := ParseErrorLog(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		core.ErrorLogs = append(core.ErrorLogs, errorLog)
+	}
+
+	for _, value := range c.Core["env"] {
+		name, envValue := value, ""
+
+		if index := strings.IndexByte(value, '='); index != -1 {
+			name, envValue = value[:index], value[index+1:]
+		}
+
+		core.Env[name] = envValue
+	}
+
+	for _, value := range c.Core["thread_pool"] {
+		pool, err := ParseThreadPool(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if core.ThreadPools[pool.Name] != nil {
+			return nil, fmt.Errorf("Thread pool %s is already defined", pool.Name)
+		}
+
+		core.ThreadPools[pool.Name] = pool
+	}
+
+	return core, nil
+}
+
+// GetEvents returns typed info about events block directives
+func (c *Config) GetEvents() (*Events, error) {
+	var err error
+
+	if c == nil {
+		return nil, fmt.Errorf("Config is nil")
+	}
+
+	events := &Events{
+		Use:               c.Events.Get("use"),
+		WorkerConnections: DEFAULT_WORKER_CONNECTIONS,
+		AcceptMutexDelay:  500 * time.Millisecond,
+	}
+
+	if value := c.Events.Get("worker_connections"); value != "" {
+		events.WorkerConnections, err = parseLimitNumber(value)
+
+		if err != nil || events.WorkerConnections == 0 {
+			return nil, fmt.Errorf("Invalid worker_connections value %s", value)
+		}
+	}
+
+	if c.Events.Get("multi_accept") != "" {
+		events.MultiAccept, err = c.Events.GetBool("multi_accept")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Events.Get("accept_mutex") != "" {
+		events.AcceptMutex, err = c.Events.GetBool("accept_mutex")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Events.Get("accept_mutex_delay") != "" {
+		events.AcceptMutexDelay, err = c.Events.GetTime("accept_mutex_delay")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseErrorLog parses error_log directive value
+func ParseErrorLog(data string) (*ErrorLog, error) {
+	args := parseArgs(data)
+
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("Invalid number of error_log parameters")
+	}
+
+	errorLog := &ErrorLog{Target: args[0], Level: LOG_LEVEL_ERROR}
+
+	if IsSyslog(errorLog.Target) {
+		var err error
+
+		errorLog.Syslog, err = ParseSyslog(errorLog.Target)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(args) == 2 {
+		if !containsString(logLevels, args[1]) {
+			return nil, fmt.Errorf("Invalid error_log level %s", args[1])
+		}
+
+		errorLog.Level = args[1]
+	}
+
+	return errorLog, nil
+}
+
+// ParseThreadPool parses thread_pool directive value
+func ParseThreadPool(data string) (*ThreadPool, error) {
+	args := parseArgs(data)
+
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("Invalid number of thread_pool parameters")
+	}
+
+	pool := &ThreadPool{Name: args[0], MaxQueue: DEFAULT_THREAD_POOL_QUEUE}
+
+	for _, arg := range args[1:] {
+		index := strings.IndexByte(arg, '=')
+
+		if index == -1 {
+			return nil, fmt.Errorf("Thread pool %s: invalid parameter %s", pool.Name, arg)
+		}
+
+		num, err := parseLimitNumber(arg[index+1:])
+
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("Thread pool %s: invalid parameter %s", pool.Name, arg)
+		case arg[:index] == "threads" && num != 0:
+			pool.Threads = num
+		case arg[:index] == "max_queue":
+			pool.MaxQueue = num
+		default:
+			return nil, fmt.Errorf("Thread pool %s: invalid parameter %s", pool.Name, arg)
+		}
+	}
+
+	if pool.Threads == 0 {
+		return nil, fmt.Errorf("Thread pool %s: threads is not set", pool.Name)
+	}
+
+	return pool, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsEnabled returns true if messages with given level will be written to log
+func (l *ErrorLog) IsEnabled(level string) bool {
+	if l == nil || l.Target == "/dev/null" {
+		return false
+	}
+
+	return getLogLevelIndex(level) >= getLogLevelIndex(l.Level)
+}
+
+// String returns string representation of error log
+func (l *ErrorLog) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return l.Target + " " + l.Level
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getLogLevelIndex returns log level severity index
+func getLogLevelIndex(level string) int {
+	for index, lvl := range logLevels {
+		if lvl == level {
+			return index
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,149 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestCore(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	core, err := config.GetCore()
+
+	c.Assert(err, IsNil)
+	c.Assert(core.User, Equals, "webkaos")
+	c.Assert(core.Group, Equals, "webkaos")
+	c.Assert(core.WorkerProcesses, Equals, 0)
+	c.Assert(core.WorkerPriority, Equals, -1)
+	c.Assert(core.WorkerRlimitNofile, Equals, 65536)
+	c.Assert(core.PID, Equals, "/var/run/webkaos.pid")
+	c.Assert(core.PCREJIT, Equals, true)
+	c.Assert(core.ErrorLogs, DeepEquals, []*ErrorLog{{Target: "/var/log/webkaos/error.log", Level: LOG_LEVEL_WARN}})
+	c.Assert(core.Modules, DeepEquals, []string{
+		"modules/ngx_http_brotli_filter_module.so",
+		"modules/ngx_http_brotli_static_module.so",
+	})
+
+	config.Core["user"] = []string{"www www-data"}
+	config.Core["worker_processes"] = []string{"4"}
+	config.Core["worker_cpu_affinity"] = []string{"0001 0010 0100 1000"}
+	config.Core["worker_rlimit_core"] = []string{"500m"}
+	config.Core["worker_shutdown_timeout"] = []string{"30s"}
+	config.Core["error_log"] = append(config.Core["error_log"], "stderr")
+	config.Core["env"] = []string{"TZ", "MALLOC_OPTIONS=J"}
+	config.Core["thread_pool"] = []string{"default threads=32", "io threads=8 max_queue=1024"}
+
+	core, err = config.GetCore()
+
+	c.Assert(err, IsNil)
+	c.Assert(core.User, Equals, "www")
+	c.Assert(core.Group, Equals, "www-data")
+	c.Assert(core.WorkerProcesses, Equals, 4)
+	c.Assert(core.WorkerCPUAffinity, DeepEquals, []string{"0001", "0010", "0100", "1000"})
+	c.Assert(core.WorkerRlimitCore, Equals, 500*MEGABYTE)
+	c.Assert(core.WorkerShutdownTimeout, Equals, 30*time.Second)
+	c.Assert(core.ErrorLogs[1].String(), Equals, "stderr error")
+	c.Assert(core.Env, DeepEquals, map[string]string{"TZ": "", "MALLOC_OPTIONS": "J"})
+	c.Assert(core.ThreadPools, DeepEquals, map[string]*ThreadPool{
+		"default": {"default", 32, DEFAULT_THREAD_POOL_QUEUE},
+		"io":      {"io", 8, 1024},
+	})
+
+	c.Assert(core.ErrorLogs[0].IsEnabled(LOG_LEVEL_ERROR), Equals, true)
+	c.Assert(core.ErrorLogs[0].IsEnabled(LOG_LEVEL_NOTICE), Equals, false)
+	c.Assert(core.ErrorLogs[1].IsEnabled(LOG_LEVEL_WARN), Equals, false)
+	c.Assert((&ErrorLog{Target: "/dev/null", Level: LOG_LEVEL_DEBUG}).IsEnabled(LOG_LEVEL_EMERG), Equals, false)
+	c.Assert((*ErrorLog)(nil).IsEnabled(LOG_LEVEL_EMERG), Equals, false)
+	c.Assert((*ErrorLog)(nil).String(), Equals, "")
+
+	for name, value := range map[string]string{
+		"user":                    "a b c",
+		"worker_processes":        "-1",
+		"worker_priority":         "-21",
+		"worker_rlimit_nofile":    "many",
+		"worker_rlimit_core":      "1t",
+		"worker_shutdown_timeout": "1x",
+		"pcre_jit":                "yes",
+		"error_log":               "/var/log/error.log verbose",
+		"thread_pool":             "io threads=0",
+	} {
+		orig := config.Core[name]
+		config.Core[name] = []string{value}
+		_, err = config.GetCore()
+		c.Assert(err, NotNil, Commentf("%s %s", name, value))
+		config.Core[name] = orig
+	}
+
+	config.Core["thread_pool"] = []string{"io threads=1", "io threads=2"}
+	_, err = config.GetCore()
+	c.Assert(err, NotNil)
+
+	for _, data := range []string{"", "a b c d", "a", "a max_queue=1", "a threads", "a threads=1 fast=1"} {
+		_, err = ParseThreadPool(data)
+		c.Assert(err, NotNil, Commentf("Thread pool: %s", data))
+	}
+
+	_, err = ParseErrorLog("")
+	c.Assert(err, NotNil)
+
+	_, err = (*Config)(nil).GetCore()
+	c.Assert(err, NotNil)
+}
+
+func (s *NginxSuite) TestEvents(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	events, err := config.GetEvents()
+
+	c.Assert(err, IsNil)
+	c.Assert(events, DeepEquals, &Events{
+		WorkerConnections: 8192,
+		AcceptMutexDelay:  500 * time.Millisecond,
+	})
+
+	config.Events["use"] = []string{"epoll"}
+	config.Events["multi_accept"] = []string{"on"}
+	config.Events["accept_mutex"] = []string{"on"}
+	config.Events["accept_mutex_delay"] = []string{"100ms"}
+
+	events, err = config.GetEvents()
+
+	c.Assert(err, IsNil)
+	c.Assert(events, DeepEquals, &Events{
+		Use:               "epoll",
+		WorkerConnections: 8192,
+		MultiAccept:       true,
+		AcceptMutex:       true,
+		AcceptMutexDelay:  100 * time.Millisecond,
+	})
+
+	for name, value := range map[string]string{
+		"worker_connections": "0",
+		"multi_accept":       "yes",
+		"accept_mutex":       "1",
+		"accept_mutex_delay": "fast",
+	} {
+		orig := config.Events[name]
+		config.Events[name] = []string{value}
+		_, err = config.GetEvents()
+		c.Assert(err, NotNil, Commentf("%s %s", name, value))
+		config.Events[name] = orig
+	}
+
+	_, err = (*Config)(nil).GetEvents()
+	c.Assert(err, NotNil)
+}
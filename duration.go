@@ -0,0 +1,243 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Parsing steps (same as in ngx_parse_time)
+const (
+	timeStepStart = iota
+	timeStepYear
+	timeStepMonth
+	timeStepWeek
+	timeStepDay
+	timeStepHour
+	timeStepMin
+	timeStepSec
+	timeStepMsec
+	timeStepLast
+)
+
+// Parsing modes
+const (
+	timeModeSec  = iota // ngx_parse_time(…, 1) — seconds precision, int32 range
+	timeModeMsec        // ngx_parse_time(…, 0) — milliseconds precision, int32 range
+	timeModeAny         // milliseconds precision, time.Duration range
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// secTimeDirectives contains directives parsed by NGINX with seconds precision
+var secTimeDirectives = []string{
+	"expires", "fastcgi_cache_valid", "open_file_cache_valid", "proxy_cache_valid",
+	"scgi_cache_valid", "ssl_session_timeout", "uwsgi_cache_valid",
+}
+
+// msecTimeDirectives contains directives parsed by NGINX with milliseconds
+// precision
+var msecTimeDirectives = []string{
+	"auth_delay", "client_body_timeout", "client_header_timeout",
+	"fastcgi_connect_timeout", "fastcgi_next_upstream_timeout",
+	"fastcgi_read_timeout", "fastcgi_send_timeout", "grpc_connect_timeout",
+	"grpc_next_upstream_timeout", "grpc_read_timeout", "grpc_send_timeout",
+	"keepalive_time", "keepalive_timeout", "lingering_time", "lingering_timeout",
+	"proxy_cache_lock_age", "proxy_cache_lock_timeout", "proxy_connect_timeout",
+	"proxy_next_upstream_timeout", "proxy_read_timeout", "proxy_send_timeout",
+	"resolver_timeout", "scgi_connect_timeout", "scgi_read_timeout",
+	"scgi_send_timeout", "send_timeout", "ssl_handshake_timeout",
+	"ssl_stapling_responder_timeout", "uwsgi_connect_timeout",
+	"uwsgi_read_timeout", "uwsgi_send_timeout",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseTime parses time value in NGINX notation (like "30", "1h30m", "2d 6h"
+// or "500ms") without NGINX range limitations
+func ParseTime(data string) (time.Duration, error) {
+	return parseTime(data, timeModeAny)
+}
+
+// ParseTimeSec parses time value same way as NGINX does for directives with
+// seconds precision (milliseconds are not allowed)
+func ParseTimeSec(data string) (time.Duration, error) {
+	return parseTime(data, timeModeSec)
+}
+
+// ParseTimeMsec parses time value same way as NGINX does for directives with
+// milliseconds precision
+func ParseTimeMsec(data string) (time.Duration, error) {
+	return parseTime(data, timeModeMsec)
+}
+
+// FormatDuration formats duration in NGINX notation (like "1d2h30m" or "500ms")
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + FormatDuration(-d)
+	}
+
+	if d < time.Millisecond {
+		return "0s"
+	}
+
+	var result strings.Builder
+
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+		{"ms", time.Millisecond},
+	} {
+		if d >= unit.size {
+			result.WriteString(strconv.FormatInt(int64(d/unit.size), 10))
+			result.WriteString(unit.suffix)
+			d %= unit.size
+		}
+	}
+
+	return result.String()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseDirectiveTime parses time value of directive with given name using
+// precision which NGINX uses for this directive
+func parseDirectiveTime(name, value string) (time.Duration, error) {
+	switch {
+	case containsString(secTimeDirectives, name):
+		return ParseTimeSec(value)
+	case containsString(msecTimeDirectives, name):
+		return ParseTimeMsec(value)
+	}
+
+	return ParseTime(value)
+}
+
+// parseTime parses time value (port of ngx_parse_time)
+func parseTime(data string, mode int) (time.Duration, error) {
+	var value, total, scale, max int64
+	var valid bool
+
+	limit := int64(math.MaxInt32)
+
+	if mode == timeModeAny {
+		limit = int64(math.MaxInt64 / time.Millisecond)
+	}
+
+	step := timeStepStart
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if c >= '0' && c <= '9' {
+			if value > (limit-int64(c-'0'))/10 {
+				return 0, fmt.Errorf("Time value %s is too big", data)
+			}
+
+			value = value*10 + int64(c-'0')
+			valid = true
+
+			continue
+		}
+
+		newStep := timeStepLast
+		scale = 1
+
+		switch c {
+		case 'y':
+			newStep, scale = timeStepYear, 60*60*24*365
+		case 'M':
+			newStep, scale = timeStepMonth, 60*60*24*30
+		case 'w':
+			newStep, scale = timeStepWeek, 60*60*24*7
+		case 'd':
+			newStep, scale = timeStepDay, 60*60*24
+		case 'h':
+			newStep, scale = timeStepHour, 60*60
+		case 'm':
+			if i+1 < len(data) && data[i+1] == 's' {
+				if mode == timeModeSec {
+					return 0, fmt.Errorf("Milliseconds are not allowed in %s", data)
+				}
+
+				i++
+				newStep = timeStepMsec
+			} else {
+				newStep, scale = timeStepMin, 60
+			}
+		case 's':
+			newStep = timeStepSec
+		case ' ':
+			newStep = timeStepLast
+		default:
+			return 0, fmt.Errorf("Invalid time value %s", data)
+		}
+
+		if newStep != timeStepLast && step >= newStep || step == timeStepLast {
+			return 0, fmt.Errorf("Invalid time value %s", data)
+		}
+
+		step = newStep
+
+		if step != timeStepMsec && mode != timeModeSec {
+			scale *= 1000
+		}
+
+		max = limit / scale
+
+		if value > max {
+			return 0, fmt.Errorf("Time value %s is too big", data)
+		}
+
+		value *= scale
+
+		if total > limit-value {
+			return 0, fmt.Errorf("Time value %s is too big", data)
+		}
+
+		total += value
+		value = 0
+
+		for i+1 < len(data) && data[i+1] == ' ' {
+			i++
+		}
+	}
+
+	if !valid {
+		return 0, fmt.Errorf("Invalid time value %s", data)
+	}
+
+	if mode != timeModeSec {
+		if value > limit/1000 {
+			return 0, fmt.Errorf("Time value %s is too big", data)
+		}
+
+		value *= 1000
+	}
+
+	if total > limit-value {
+		return 0, fmt.Errorf("Time value %s is too big", data)
+	}
+
+	if mode == timeModeSec {
+		return time.Duration(total+value) * time.Second, nil
+	}
+
+	return time.Duration(total+value) * time.Millisecond, nil
+}
@@ -0,0 +1,102 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestTimeParsing(c *C) {
+	for data, dur := range map[string]time.Duration{
+		"0":             0,
+		"90":            90 * time.Second,
+		"500ms":         500 * time.Millisecond,
+		"1h30m":         90 * time.Minute,
+		"2d 6h 30m 15s": 54*time.Hour + 30*time.Minute + 15*time.Second,
+		"1y2M3w":        (365 + 60 + 21) * 24 * time.Hour,
+		"1m500ms":       time.Minute + 500*time.Millisecond,
+		"1s 500":        501 * time.Second,
+		"200y":          200 * 365 * 24 * time.Hour,
+	} {
+		d, err := ParseTime(data)
+
+		c.Assert(err, IsNil, Commentf("Time: %s", data))
+		c.Assert(d, Equals, dur, Commentf("Time: %s", data))
+	}
+
+	for _, data := range []string{
+		"", "abc", "1ms5x", "3u", "30m1h", "1h1h", "5 6h", "-1", "ms", "1.5s",
+		"9999999999999999y",
+	} {
+		_, err := ParseTime(data)
+		c.Assert(err, NotNil, Commentf("Time: %s", data))
+	}
+
+	d, err := ParseTimeSec("1d")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 24*time.Hour)
+
+	d, err = ParseTimeSec("68y")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 68*365*24*time.Hour)
+
+	_, err = ParseTimeSec("500ms")
+	c.Assert(err, NotNil)
+	_, err = ParseTimeSec("69y")
+	c.Assert(err, NotNil)
+	_, err = ParseTimeSec("2147483648")
+	c.Assert(err, NotNil)
+
+	d, err = ParseTimeMsec("24d")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 24*24*time.Hour)
+
+	d, err = ParseTimeMsec("100ms")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 100*time.Millisecond)
+
+	_, err = ParseTimeMsec("25d")
+	c.Assert(err, NotNil)
+	_, err = ParseTimeMsec("24d 1d")
+	c.Assert(err, NotNil)
+	_, err = ParseTimeMsec("2147484")
+	c.Assert(err, NotNil)
+	_, err = ParseTimeMsec("24d 80000000ms")
+	c.Assert(err, NotNil)
+
+	p := Properties{
+		"proxy_read_timeout":  {"30d"},
+		"ssl_session_timeout": {"10ms"},
+		"unknown_timeout":     {"30d 10ms"},
+	}
+
+	_, err = p.GetTime("proxy_read_timeout")
+	c.Assert(err, NotNil)
+	_, err = p.GetTime("ssl_session_timeout")
+	c.Assert(err, NotNil)
+	d, err = p.GetTime("unknown_timeout")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 30*24*time.Hour+10*time.Millisecond)
+
+	c.Assert(FormatDuration(0), Equals, "0s")
+	c.Assert(FormatDuration(time.Microsecond), Equals, "0s")
+	c.Assert(FormatDuration(500*time.Millisecond), Equals, "500ms")
+	c.Assert(FormatDuration(90*time.Second), Equals, "1m30s")
+	c.Assert(FormatDuration(54*time.Hour+15*time.Second+20*time.Millisecond), Equals, "2d6h15s20ms")
+	c.Assert(FormatDuration(-time.Hour), Equals, "-1h")
+
+	for _, dur := range []time.Duration{time.Second, 1234567 * time.Millisecond, 400 * 24 * time.Hour} {
+		d, err = ParseTime(FormatDuration(dur))
+		c.Assert(err, IsNil)
+		c.Assert(d, Equals, dur)
+	}
+}
@@ -0,0 +1,285 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Geo contains info about geo block
+type Geo struct {
+	Source         string
+	Variable       string
+	Default        string
+	Ranges         bool
+	ProxyRecursive bool
+	Proxies        []*net.IPNet
+	Entries        []*GeoEntry
+	Includes       []string
+	Parent         *HTTP
+}
+
+// GeoEntry contains geo entry (network or range of addresses)
+type GeoEntry struct {
+	Network *net.IPNet
+	From    net.IP
+	To      net.IP
+	Value   string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Lookup returns value for given IP address
+func (g *Geo) Lookup(ip net.IP) string {
+	if g == nil {
+		return ""
+	}
+
+	ip = normalizeIP(ip)
+
+	if ip == nil {
+		return g.Default
+	}
+
+	if g.Ranges {
+		// Later ranges override earlier ones
+		for i := len(g.Entries) - 1; i >= 0; i-- {
+			entry := g.Entries[i]
+
+			if len(entry.From) == len(ip) &&
+				bytes.Compare(ip, entry.From) >= 0 &&
+				bytes.Compare(ip, entry.To) <= 0 {
+				return entry.Value
+			}
+		}
+
+		return g.Default
+	}
+
+	var result *GeoEntry
+	var resultSize int
+
+	for _, entry := range g.Entries {
+		if !entry.Network.Contains(ip) {
+			continue
+		}
+
+		size, _ := entry.Network.Mask.Size()
+
+		if result == nil || size >= resultSize {
+			result, resultSize = entry, size
+		}
+	}
+
+	if result == nil {
+		return g.Default
+	}
+
+	return result.Value
+}
+
+// IsTrustedProxy returns true if given address is in the list of trusted proxies
+func (g *Geo) IsTrustedProxy(ip net.IP) bool {
+	if g == nil {
+		return false
+	}
+
+	for _, network := range g.Proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseGeoBlock parses geo block
+func parseGeoBlock(data []string, cursor int, args []string) (int, *Geo, error) {
+	geo := &Geo{Source: "$remote_addr"}
+
+	switch len(args) {
+	case 1:
+		geo.Variable = args[0]
+	case 2:
+		geo.Source, geo.Variable = args[0], args[1]
+	default:
+		return -1, nil, fmt.Errorf("Geo block must have target variable")
+	}
+
+	varName, size := parseVariableName(geo.Variable)
+
+	if varName == "" || size != len(geo.Variable) {
+		return -1, nil, fmt.Errorf("Invalid geo target variable %s", geo.Variable)
+	}
+
+	geo.Variable = varName
+	dataLen := len(data)
+
+	for {
+		if cursor >= dataLen {
+			break
+		}
+
+		line := data[cursor]
+
+		if isBlockEnd(line) {
+			return cursor + 1, geo, nil
+		}
+
+		err := geo.parseEntry(parseArgs(cleanData(line)))
+
+		if err != nil {
+			return -1, nil, err
+		}
+
+		cursor++
+	}
+
+	return -1, nil, fmt.Errorf("Can't find block end")
+}
+
+// parseEntry parses geo entry
+func (g *Geo) parseEntry(args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "ranges":
+		if len(g.Entries) != 0 {
+			return fmt.Errorf("Geo %s: \"ranges\" must be defined before any entry", g.Variable)
+		}
+
+		g.Ranges = true
+
+	case len(args) == 1 && args[0] == "proxy_recursive":
+		g.ProxyRecursive = true
+
+	case len(args) == 2 && args[0] == "default":
+		g.Default = args[1]
+
+	case len(args) == 2 && args[0] == "include":
+		g.Includes = append(g.Includes, args[1])
+
+	case len(args) == 2 && args[0] == "proxy":
+		network, err := parseGeoNetwork(args[1])
+
+		if err != nil {
+			return fmt.Errorf("Geo %s: %v", g.Variable, err)
+		}
+
+		g.Proxies = append(g.Proxies, network)
+
+	case len(args) == 2 && args[0] == "delete":
+		return g.deleteEntry(args[1])
+
+	case len(args) == 2:
+		entry, err := g.parseAddress(args[0])
+
+		if err != nil {
+			return fmt.Errorf("Geo %s: %v", g.Variable, err)
+		}
+
+		entry.Value = args[1]
+		g.Entries = append(g.Entries, entry)
+
+	default:
+		return fmt.Errorf("Invalid number of parameters in geo %s entry", g.Variable)
+	}
+
+	return nil
+}
+
+// parseAddress parses network or range of addresses
+func (g *Geo) parseAddress(data string) (*GeoEntry, error) {
+	if !g.Ranges {
+		network, err := parseGeoNetwork(data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &GeoEntry{Network: network}, nil
+	}
+
+	addrs := strings.Split(data, "-")
+
+	if len(addrs) != 2 {
+		return nil, fmt.Errorf("Invalid range %s", data)
+	}
+
+	from, to := normalizeIP(net.ParseIP(addrs[0])), normalizeIP(net.ParseIP(addrs[1]))
+
+	if from == nil || to == nil || len(from) != len(to) || bytes.Compare(from, to) > 0 {
+		return nil, fmt.Errorf("Invalid range %s", data)
+	}
+
+	return &GeoEntry{From: from, To: to}, nil
+}
+
+// deleteEntry removes network or range from geo entries
+func (g *Geo) deleteEntry(data string) error {
+	target, err := g.parseAddress(data)
+
+	if err != nil {
+		return fmt.Errorf("Geo %s: %v", g.Variable, err)
+	}
+
+	var entries []*GeoEntry
+
+	for _, entry := range g.Entries {
+		if g.Ranges && entry.From.Equal(target.From) && entry.To.Equal(target.To) {
+			continue
+		}
+
+		if !g.Ranges && entry.Network.String() == target.Network.String() {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	g.Entries = entries
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseGeoNetwork parses network in CIDR notation or single address
+func parseGeoNetwork(data string) (*net.IPNet, error) {
+	if strings.Contains(data, "/") {
+		_, network, err := net.ParseCIDR(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid network %s", data)
+		}
+
+		return network, nil
+	}
+
+	ip := normalizeIP(net.ParseIP(data))
+
+	if ip == nil {
+		return nil, fmt.Errorf("Invalid address %s", data)
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}, nil
+}
+
+// normalizeIP converts IPv4 addresses to 4-byte representation
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip
+}
@@ -0,0 +1,137 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"net"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestGeoParsing(c *C) {
+	data := []string{
+		"geo $remote_addr $blocked {",
+		"default        1;",
+		"proxy          192.168.100.0/24;",
+		"proxy          10.0.0.1;",
+		"proxy_recursive;",
+		"include        conf/geo.conf;",
+		"127.0.0.1      0;",
+		"10.0.0.0/8     0;",
+		"10.1.0.0/16    1;",
+		"10.2.0.0/16    0;",
+		"10.2.0.0/16    2;",
+		"10.3.0.0/16    0;",
+		"delete         10.3.0.0/16;",
+		"2001:db8::/32  0;",
+		"}",
+		"}",
+	}
+
+	_, http, err := parseHTTPBlock(data, 0)
+
+	c.Assert(err, IsNil)
+
+	geo := http.Geos["blocked"]
+
+	c.Assert(geo, NotNil)
+	c.Assert(geo.Parent, Equals, http)
+	c.Assert(geo.Source, Equals, "$remote_addr")
+	c.Assert(geo.ProxyRecursive, Equals, true)
+	c.Assert(geo.Proxies, HasLen, 2)
+	c.Assert(geo.Includes, DeepEquals, []string{"conf/geo.conf"})
+	c.Assert(geo.Entries, HasLen, 6)
+
+	c.Assert(geo.Lookup(net.ParseIP("127.0.0.1")), Equals, "0")
+	c.Assert(geo.Lookup(net.ParseIP("127.0.0.2")), Equals, "1")
+	c.Assert(geo.Lookup(net.ParseIP("10.0.0.5")), Equals, "0")
+	c.Assert(geo.Lookup(net.ParseIP("10.1.2.3")), Equals, "1")
+	c.Assert(geo.Lookup(net.ParseIP("10.2.2.3")), Equals, "2")
+	c.Assert(geo.Lookup(net.ParseIP("10.3.2.3")), Equals, "0")
+	c.Assert(geo.Lookup(net.ParseIP("2001:db8::1")), Equals, "0")
+	c.Assert(geo.Lookup(net.ParseIP("2001:db9::1")), Equals, "1")
+	c.Assert(geo.Lookup(nil), Equals, "1")
+
+	c.Assert(geo.IsTrustedProxy(net.ParseIP("192.168.100.15")), Equals, true)
+	c.Assert(geo.IsTrustedProxy(net.ParseIP("10.0.0.1")), Equals, true)
+	c.Assert(geo.IsTrustedProxy(net.ParseIP("10.0.0.2")), Equals, false)
+
+	vars := http.GetVariables()
+	c.Assert(vars.Definitions[0].Name, Equals, "blocked")
+	c.Assert(vars.Definitions[0].Kind, Equals, VAR_MAPPED)
+	c.Assert(vars.Find("remote_addr")[0].Context, Equals, "http/geo[$blocked]")
+
+	var nilGeo *Geo
+	c.Assert(nilGeo.Lookup(net.ParseIP("127.0.0.1")), Equals, "")
+	c.Assert(nilGeo.IsTrustedProxy(net.ParseIP("127.0.0.1")), Equals, false)
+}
+
+func (s *NginxSuite) TestGeoRanges(c *C) {
+	data := []string{
+		"ranges;",
+		"default                    ZZ;",
+		"127.0.0.0-127.0.0.255      US;",
+		"127.0.0.10-127.0.0.20      RU;",
+		"127.0.1.0-127.0.1.255      DE;",
+		"delete 127.0.1.0-127.0.1.255;",
+		"}",
+	}
+
+	_, geo, err := parseGeoBlock(data, 0, []string{"$country"})
+
+	c.Assert(err, IsNil)
+	c.Assert(geo.Source, Equals, "$remote_addr")
+	c.Assert(geo.Ranges, Equals, true)
+	c.Assert(geo.Entries, HasLen, 2)
+
+	c.Assert(geo.Lookup(net.ParseIP("127.0.0.1")), Equals, "US")
+	c.Assert(geo.Lookup(net.ParseIP("127.0.0.15")), Equals, "RU")
+	c.Assert(geo.Lookup(net.ParseIP("127.0.1.15")), Equals, "ZZ")
+	c.Assert(geo.Lookup(net.ParseIP("::1")), Equals, "ZZ")
+}
+
+func (s *NginxSuite) TestGeoErrors(c *C) {
+	_, _, err := parseGeoBlock([]string{"}"}, 0, nil)
+	c.Assert(err, NotNil)
+	_, _, err = parseGeoBlock([]string{"}"}, 0, []string{"country"})
+	c.Assert(err, NotNil)
+	_, _, err = parseGeoBlock([]string{"127.0.0.1 1;"}, 0, []string{"$country"})
+	c.Assert(err, NotNil)
+
+	badEntries := []string{
+		"a b c;",
+		"127.0.0.300 1;",
+		"127.0.0.0/40 1;",
+		"proxy abc;",
+		"delete abc;",
+	}
+
+	for _, entry := range badEntries {
+		_, _, err = parseGeoBlock([]string{entry, "}"}, 0, []string{"$country"})
+		c.Assert(err, NotNil, Commentf("Entry: %s", entry))
+	}
+
+	badRanges := []string{
+		"127.0.0.1 1;",
+		"127.0.0.2-127.0.0.1 1;",
+		"127.0.0.1-::1 1;",
+	}
+
+	for _, entry := range badRanges {
+		_, _, err = parseGeoBlock([]string{"ranges;", entry, "}"}, 0, []string{"$country"})
+		c.Assert(err, NotNil, Commentf("Entry: %s", entry))
+	}
+
+	_, _, err = parseGeoBlock([]string{"127.0.0.1 1;", "ranges;", "}"}, 0, []string{"$country"})
+	c.Assert(err, NotNil)
+
+	_, _, err = parseHTTPBlock([]string{"geo {", "}", "}"}, 0)
+	c.Assert(err, NotNil)
+}
@@ -9,6 +9,8 @@ package nginx
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,6 +20,9 @@ import (
 
 var errEmptyProp = fmt.Errorf("Value is empty")
 
+// namedGroupRegex is regex for named groups in PCRE and Go syntax
+var namedGroupRegex = regexp.MustCompile(`\(\?(?:P?<([A-Za-z_][A-Za-z0-9_]*)>|'([A-Za-z_][A-Za-z0-9_]*)')`)
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // String returns config as a string
@@ -137,7 +142,7 @@ func (p Properties) GetInt(name string) (int64, error) {
 }
 
 // GetBuf returns property with given name as buffer
-func (p Properties) GetBuf(name string) (int64, int64, error) {
+func (p Properties) GetBuf(name string) (int64, Size, error) {
 	v := p.Get(name)
 
 	if v == "" {
@@ -148,14 +153,14 @@ func (p Properties) GetBuf(name string) (int64, int64, error) {
 }
 
 // GetSize parses and returns property with given name as size in bytes
-func (p Properties) GetSize(name string) (int64, error) {
+func (p Properties) GetSize(name string) (Size, error) {
 	v := p.Get(name)
 
 	if v == "" {
 		return 0, errEmptyProp
 	}
 
-	return parseSize(v)
+	return ParseSize(v)
 }
 
 // GetTime parses and returns property with given name as time duration
@@ -166,7 +171,24 @@ func (p Properties) GetTime(name string) (time.Duration, error) {
 		return 0, errEmptyProp
 	}
 
-	return parseTime(v)
+	return parseDirectiveTime(name, v)
+}
+
+// GetAll returns arguments of all properties with given name
+func (p Properties) GetAll(name string) [][]string {
+	var result [][]string
+
+	for _, v := range p[name] {
+		result = append(result, parseArgs(v))
+	}
+
+	return result
+}
+
+// GetMap returns key/value properties with given name (like proxy_set_header)
+// as map (first argument → second argument)
+func (p Properties) GetMap(name string) map[string]string {
+	return argsToMap(p.GetAll(name))
 }
 
 ////////////////////////////////////////////////////////////////////////////////// //
@@ -215,7 +237,7 @@ func (p *ConditionalProperties) GetInt(name string) (int64, error) {
 }
 
 // GetBuf returns property with given name as buffer
-func (p *ConditionalProperties) GetBuf(name string) (int64, int64, error) {
+func (p *ConditionalProperties) GetBuf(name string) (int64, Size, error) {
 	v := p.Get(name)
 
 	if v == "" {
@@ -226,14 +248,14 @@ func (p *ConditionalProperties) GetBuf(name string) (int64, int64, error) {
 }
 
 // GetSize parses and returns property with given name as size in bytes
-func (p *ConditionalProperties) GetSize(name string) (int64, error) {
+func (p *ConditionalProperties) GetSize(name string) (Size, error) {
 	v := p.Get(name)
 
 	if v == "" {
 		return 0, errEmptyProp
 	}
 
-	return parseSize(v)
+	return ParseSize(v)
 }
 
 // GetTime parses and returns property with given name as time duration
@@ -244,11 +266,307 @@ func (p *ConditionalProperties) GetTime(name string) (time.Duration, error) {
 		return 0, errEmptyProp
 	}
 
-	return parseTime(v)
+	return parseDirectiveTime(name, v)
+}
+
+// GetAll returns arguments of all properties with given name which are defined
+// without condition or inside conditions with given IDs
+func (p *ConditionalProperties) GetAll(name string, conditionIDs ...int) [][]string {
+	var result [][]string
+
+	if p == nil || p.Data == nil {
+		return nil
+	}
+
+	for _, prop := range p.Data[name] {
+		if prop.ConditionID != -1 && !containsInt(conditionIDs, prop.ConditionID) {
+			continue
+		}
+
+		result = append(result, parseArgs(prop.Value))
+	}
+
+	return result
+}
+
+// GetMap returns key/value properties with given name (like proxy_set_header)
+// as map (first argument → second argument). Only properties defined without
+// condition or inside conditions with given IDs are used.
+func (p *ConditionalProperties) GetMap(name string, conditionIDs ...int) map[string]string {
+	return argsToMap(p.GetAll(name, conditionIDs...))
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// getInheritedValues returns unconditional values of property with given name
+// from the most specific level (server or HTTP block) where property is defined
+func (s *Server) getInheritedValues(name string) []string {
+	values := s.Properties.getUnconditionalValues(name)
+
+	if len(values) != 0 || s.Parent == nil {
+		return values
+	}
+
+	return s.Parent.Properties[name]
+}
+
+// getInheritedValues returns unconditional values of property with given name
+// from the most specific level (location, server or HTTP block) where property
+// is defined
+func (l *Location) getInheritedValues(name string) []string {
+	values := l.Properties.getUnconditionalValues(name)
+
+	if len(values) != 0 || l.Parent == nil {
+		return values
+	}
+
+	return l.Parent.getInheritedValues(name)
+}
+
+// getUnconditionalValues returns values of property which are not defined
+// in "if" blocks
+func (p *ConditionalProperties) getUnconditionalValues(name string) []string {
+	var result []string
+
+	if p == nil {
+		return nil
+	}
+
+	for _, prop := range p.Data[name] {
+		if prop.ConditionID == -1 {
+			result = append(result, prop.Value)
+		}
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// propertyHandler is function for processing property with given name and value
+// defined in given context
+type propertyHandler func(context, name, value string)
+
+// walk calls handler for every property (and every "if" condition) defined in
+// http, server and location blocks
+func (h *HTTP) walk(handler propertyHandler) {
+	if h == nil {
+		return
+	}
+
+	var names []string
+
+	for name := range h.Properties {
+		names = append(names, name)
+	}
+
+	for _, name := range sortStrings(names) {
+		for _, value := range h.Properties[name] {
+			handler("http", name, value)
+		}
+	}
+
+	for serverIndex, server := range h.Servers {
+		serverContext := getServerContext(serverIndex)
+		server.Properties.walk(serverContext, handler)
+
+		for _, location := range server.Locations {
+			location.Properties.walk(getLocationContext(serverContext, location), handler)
+		}
+	}
+}
+
+// walk calls handler for every conditional property and condition
+func (p *ConditionalProperties) walk(context string, handler propertyHandler) {
+	if p == nil {
+		return
+	}
+
+	for conditionID, condition := range p.Conditions {
+		handler(getConditionContext(context, conditionID), "if", condition)
+	}
+
+	var names []string
+
+	for name := range p.Data {
+		names = append(names, name)
+	}
+
+	for _, name := range sortStrings(names) {
+		for _, prop := range p.Data[name] {
+			if prop.ConditionID == -1 {
+				handler(context, name, prop.Value)
+			} else {
+				handler(getConditionContext(context, prop.ConditionID), name, prop.Value)
+			}
+		}
+	}
+}
+
+// getServerContext returns context name for server with given index
+func getServerContext(index int) string {
+	return "http/server[" + strconv.Itoa(index) + "]"
+}
+
+// getLocationContext returns context name for given location
+func getLocationContext(serverContext string, location *Location) string {
+	if location.Modifier == "" {
+		return serverContext + "/location[" + location.URI + "]"
+	}
+
+	return serverContext + "/location[" + location.Modifier + " " + location.URI + "]"
+}
+
+// getConditionContext returns context name for condition with given ID
+func getConditionContext(context string, conditionID int) string {
+	return context + "/if[" + strconv.Itoa(conditionID) + "]"
+}
+
+// sortStrings sorts given slice with strings and returns it
+func sortStrings(data []string) []string {
+	sort.Strings(data)
+	return data
+}
+
+// compileRegexp compiles PCRE-like regular expression
+func compileRegexp(expr string, caseless bool) (*regexp.Regexp, error) {
+	expr = namedGroupRegex.ReplaceAllString(expr, "(?P<$1$2>")
+
+	if caseless {
+		expr = "(?i)" + expr
+	}
+
+	return regexp.Compile(expr)
+}
+
+// expandVariables replaces references to variables in given string with values
+// from given map (unknown variables are replaced by an empty string)
+func expandVariables(data string, vars map[string]string) string {
+	return replaceVariables(data, func(name, ref string) string {
+		return vars[name]
+	})
+}
+
+// expandCaptures replaces references to regex captures ($1..$9 and named
+// captures) in given string with captured values
+func expandCaptures(data string, re *regexp.Regexp, groups []string) string {
+	if re == nil {
+		return data
+	}
+
+	names := re.SubexpNames()
+
+	return replaceVariables(data, func(name, ref string) string {
+		groupIndex := -1
+
+		if isNumericVariable(name) {
+			groupIndex = int(name[0] - '0')
+		} else {
+			for i, n := range names {
+				if n == name {
+					groupIndex = i
+					break
+				}
+			}
+		}
+
+		switch {
+		case groupIndex == -1:
+			return ref
+		case groupIndex < len(groups):
+			return groups[groupIndex]
+		}
+
+		return ""
+	})
+}
+
+// replaceVariables replaces all variables in given string using resolver
+// function (resolver gets variable name and full reference text)
+func replaceVariables(data string, resolver func(name, ref string) string) string {
+	if !strings.Contains(data, "$") {
+		return data
+	}
+
+	var buf strings.Builder
+
+	for {
+		index := strings.IndexByte(data, '$')
+
+		if index == -1 {
+			buf.WriteString(data)
+			break
+		}
+
+		buf.WriteString(data[:index])
+		name, size := parseVariableName(data[index:])
+
+		if name == "" {
+			buf.WriteByte('$')
+			data = data[index+1:]
+			continue
+		}
+
+		buf.WriteString(resolver(name, data[index:index+size]))
+		data = data[index+size:]
+	}
+
+	return buf.String()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseZoneSpec parses shared memory zone specification (name:size)
+func parseZoneSpec(data string) (string, Size, error) {
+	index := strings.LastIndexByte(data, ':')
+
+	if index < 1 {
+		return "", 0, fmt.Errorf("Invalid zone %s", data)
+	}
+
+	size, err := ParseSize(data[index+1:])
+
+	if err != nil || size == 0 {
+		return "", 0, fmt.Errorf("Invalid zone %s size", data[:index])
+	}
+
+	return data[:index], size, nil
+}
+
+// argsToMap converts slice with arguments to map (first argument → second
+// argument), properties with one argument have empty value
+func argsToMap(data [][]string) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string)
+
+	for _, args := range data {
+		switch len(args) {
+		case 0:
+			continue
+		case 1:
+			result[args[0]] = ""
+		default:
+			result[args[0]] = args[1]
+		}
+	}
+
+	return result
+}
+
+// containsInt returns true if slice contains given value
+func containsInt(data []int, value int) bool {
+	for _, v := range data {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 // isProtocolSupported checks if protocol is supported
 func isProtocolSupported(protocolList []string, protocol string) bool {
 	for _, p := range protocolList {
@@ -281,7 +599,7 @@ func parseInt(s string) (int64, error) {
 	return strconv.ParseInt(s, 10, 64)
 }
 
-func parseBuffers(s string) (int64, int64, error) {
+func parseBuffers(s string) (int64, Size, error) {
 	if strings.Count(s, " ") != 1 {
 		return 0, 0, fmt.Errorf("Wrong buffer format value")
 	}
@@ -293,7 +611,7 @@ func parseBuffers(s string) (int64, int64, error) {
 		return 0, 0, err
 	}
 
-	size, err := parseSize(buf[1])
+	size, err := ParseSize(buf[1])
 
 	if err != nil {
 		return 0, 0, err
@@ -301,67 +619,3 @@ func parseBuffers(s string) (int64, int64, error) {
 
 	return num, size, nil
 }
-
-func parseSize(s string) (int64, error) {
-	mod := strings.Trim(s, "0123456789")
-	val := strings.Trim(s, "kKmMgG")
-
-	valInt, _ := strconv.ParseInt(val, 10, 64)
-
-	switch mod {
-	case "k", "K":
-		return valInt * 1024, nil
-	case "m", "M":
-		return valInt * 1024 * 1024, nil
-	case "g", "G":
-		return valInt * 1024 * 1024 * 1024, nil
-	case "":
-		return valInt, nil
-	}
-
-	return 0, fmt.Errorf("Unsupported measurement unit %s", mod)
-}
-
-func parseTime(t string) (time.Duration, error) {
-	var result time.Duration
-
-	for _, part := range strings.Fields(t) {
-		dur, err := parseTimePeriod(part)
-
-		if err != nil {
-			return 0, err
-		}
-
-		result += dur
-	}
-
-	return result, nil
-}
-
-func parseTimePeriod(p string) (time.Duration, error) {
-	mod := strings.Trim(p, "0123456789")
-	val := strings.Trim(p, "mshdwMy")
-
-	valInt, _ := strconv.ParseInt(val, 10, 64)
-
-	switch mod {
-	case "ms":
-		return time.Duration(valInt) * time.Millisecond, nil
-	case "s", "":
-		return time.Duration(valInt) * time.Second, nil
-	case "m":
-		return time.Duration(valInt) * time.Minute, nil
-	case "h":
-		return time.Duration(valInt) * time.Hour, nil
-	case "d":
-		return time.Duration(valInt) * time.Hour * 24, nil
-	case "w":
-		return time.Duration(valInt) * time.Hour * 24 * 7, nil
-	case "M":
-		return time.Duration(valInt) * time.Hour * 24 * 30, nil
-	case "y":
-		return time.Duration(valInt) * time.Hour * 24 * 365, nil
-	}
-
-	return 0, fmt.Errorf("Unsupported time unit %s", mod)
-}
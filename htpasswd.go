@@ -0,0 +1,360 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	HASH_UNKNOWN      = "unknown"
+	HASH_PLAIN        = "plain"
+	HASH_SHA          = "sha"
+	HASH_SSHA         = "ssha"
+	HASH_APR1         = "apr1"
+	HASH_MD5_CRYPT    = "md5-crypt"
+	HASH_SHA256_CRYPT = "sha256-crypt"
+	HASH_SHA512_CRYPT = "sha512-crypt"
+	HASH_DES_CRYPT    = "des-crypt"
+	HASH_BCRYPT       = "bcrypt"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Htpasswd contains info about password file
+type Htpasswd struct {
+	File  string
+	Users []*HtpasswdUser
+}
+
+// HtpasswdUser contains info about user from password file
+type HtpasswdUser struct {
+	Name   string
+	Hash   string
+	Scheme string
+	Line   int
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// apr1Alphabet is alphabet used for encoding MD5-based hashes
+const apr1Alphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReadHtpasswd reads and parses password file
+func ReadHtpasswd(file string) (*Htpasswd, error) {
+	fd, err := os.OpenFile(file, os.O_RDONLY, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+
+	htpasswd := &Htpasswd{File: file}
+	scanner := bufio.NewScanner(fd)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		index := strings.IndexByte(line, ':')
+
+		if index < 1 {
+			return nil, fmt.Errorf("Invalid line %d in password file %s", lineNum, file)
+		}
+
+		name, hash := line[:index], line[index+1:]
+
+		if index = strings.IndexByte(hash, ':'); index != -1 {
+			hash = hash[:index]
+		}
+
+		htpasswd.Users = append(htpasswd.Users, &HtpasswdUser{
+			Name:   name,
+			Hash:   hash,
+			Scheme: getHashScheme(hash),
+			Line:   lineNum,
+		})
+	}
+
+	return htpasswd, scanner.Err()
+}
+
+// ReadHtpasswd reads password file (relative paths are resolved from
+// configuration root)
+func (c *Config) ReadHtpasswd(file string) (*Htpasswd, error) {
+	if strings.Contains(file, "$") {
+		return nil, fmt.Errorf("Path %s contains variables", file)
+	}
+
+	return ReadHtpasswd(getAbsPath(c.Root, file))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Find returns user with given name (NGINX uses the first matching line)
+func (h *Htpasswd) Find(name string) *HtpasswdUser {
+	if h == nil {
+		return nil
+	}
+
+	for _, user := range h.Users {
+		if user.Name == name {
+			return user
+		}
+	}
+
+	return nil
+}
+
+// Verify checks user name and password
+func (h *Htpasswd) Verify(name, password string) (bool, error) {
+	user := h.Find(name)
+
+	if user == nil {
+		return false, nil
+	}
+
+	return user.Verify(password)
+}
+
+// Problems returns slice with info about weak, unsupported and duplicate entries
+func (h *Htpasswd) Problems() []string {
+	var result []string
+
+	if h == nil {
+		return nil
+	}
+
+	names := make(map[string]int)
+
+	for _, user := range h.Users {
+		switch {
+		case names[user.Name] != 0:
+			result = append(result, fmt.Sprintf(
+				"Line %d: user %s is already defined on line %d",
+				user.Line, user.Name, names[user.Name],
+			))
+			continue
+		case !user.IsSupported():
+			result = append(result, fmt.Sprintf(
+				"Line %d: user %s has hash with scheme unsupported by NGINX (%s)",
+				user.Line, user.Name, user.Scheme,
+			))
+		case user.IsWeak():
+			result = append(result, fmt.Sprintf(
+				"Line %d: user %s has weak hash (%s)",
+				user.Line, user.Name, user.Scheme,
+			))
+		}
+
+		names[user.Name] = user.Line
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsSupported returns true if hash scheme is supported by NGINX on Linux
+func (u *HtpasswdUser) IsSupported() bool {
+	switch u.Scheme {
+	case HASH_UNKNOWN, HASH_BCRYPT:
+		return false
+	}
+
+	return true
+}
+
+// IsWeak returns true if hash scheme is considered weak
+func (u *HtpasswdUser) IsWeak() bool {
+	switch u.Scheme {
+	case HASH_PLAIN, HASH_SHA, HASH_DES_CRYPT, HASH_APR1, HASH_MD5_CRYPT:
+		return true
+	}
+
+	return false
+}
+
+// Verify checks password
+func (u *HtpasswdUser) Verify(password string) (bool, error) {
+	var hash string
+
+	switch u.Scheme {
+	case HASH_PLAIN:
+		hash = "{PLAIN}" + password
+	case HASH_SHA:
+		sum := sha1.Sum([]byte(password))
+		hash = "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	case HASH_SSHA:
+		data, err := base64.StdEncoding.DecodeString(u.Hash[6:])
+
+		if err != nil || len(data) <= sha1.Size {
+			return false, fmt.Errorf("Invalid SSHA hash for user %s", u.Name)
+		}
+
+		salt := data[sha1.Size:]
+		sum := sha1.Sum(append([]byte(password), salt...))
+		hash = "{SSHA}" + base64.StdEncoding.EncodeToString(append(sum[:], salt...))
+	case HASH_APR1, HASH_MD5_CRYPT:
+		magic := "$1$"
+
+		if u.Scheme == HASH_APR1 {
+			magic = "$apr1$"
+		}
+
+		salt := strings.TrimPrefix(u.Hash, magic)
+
+		if index := strings.IndexByte(salt, '$'); index != -1 {
+			salt = salt[:index]
+		}
+
+		hash = md5Crypt(password, salt, magic)
+	default:
+		return false, fmt.Errorf("Verification of %s hashes is not supported", u.Scheme)
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(u.Hash)) == 1, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getHashScheme returns hash scheme
+func getHashScheme(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "{PLAIN}"):
+		return HASH_PLAIN
+	case strings.HasPrefix(hash, "{SHA}"):
+		return HASH_SHA
+	case strings.HasPrefix(hash, "{SSHA}"):
+		return HASH_SSHA
+	case strings.HasPrefix(hash, "$apr1$"):
+		return HASH_APR1
+	case strings.HasPrefix(hash, "$1$"):
+		return HASH_MD5_CRYPT
+	case strings.HasPrefix(hash, "$5$"):
+		return HASH_SHA256_CRYPT
+	case strings.HasPrefix(hash, "$6$"):
+		return HASH_SHA512_CRYPT
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"),
+		strings.HasPrefix(hash, "$2y$"):
+		return HASH_BCRYPT
+	case len(hash) == 13 && isCryptString(hash):
+		return HASH_DES_CRYPT
+	}
+
+	return HASH_UNKNOWN
+}
+
+// isCryptString returns true if string contains only crypt alphabet symbols
+func isCryptString(data string) bool {
+	for _, r := range data {
+		if !strings.ContainsRune(apr1Alphabet, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// md5Crypt calculates MD5-based crypt hash (same as ngx_crypt_apr1)
+func md5Crypt(password, salt, magic string) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	pass := []byte(password)
+
+	alt := md5.Sum([]byte(password + salt + password))
+
+	ctx := md5.New()
+	ctx.Write([]byte(password + magic + salt))
+
+	for n := len(pass); n > 0; n -= 16 {
+		if n > 16 {
+			ctx.Write(alt[:])
+		} else {
+			ctx.Write(alt[:n])
+		}
+	}
+
+	for i := len(pass); i != 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(pass[:1])
+		}
+	}
+
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		ctx.Reset()
+
+		if i&1 != 0 {
+			ctx.Write(pass)
+		} else {
+			ctx.Write(final)
+		}
+
+		if i%3 != 0 {
+			ctx.Write([]byte(salt))
+		}
+
+		if i%7 != 0 {
+			ctx.Write(pass)
+		}
+
+		if i&1 != 0 {
+			ctx.Write(final)
+		} else {
+			ctx.Write(pass)
+		}
+
+		final = ctx.Sum(nil)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(magic + salt + "$")
+
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		v := uint(final[group[0]])<<16 | uint(final[group[1]])<<8 | uint(final[group[2]])
+		writeCryptBase64(&buf, v, 4)
+	}
+
+	writeCryptBase64(&buf, uint(final[11]), 2)
+
+	return buf.String()
+}
+
+// writeCryptBase64 writes value encoded with crypt base64 alphabet
+func writeCryptBase64(buf *bytes.Buffer, v uint, n int) {
+	for ; n > 0; n-- {
+		buf.WriteByte(apr1Alphabet[v&0x3f])
+		v >>= 6
+	}
+}
@@ -0,0 +1,106 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const htpasswdTestData = `# Test users
+plain:{PLAIN}secret
+sha:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=
+ssha:{SSHA}gVK8WC9YyFT1gMsQHTGCgT3sSv5zYWx0:comment
+apr1:$apr1$abcdefgh$h9FWgUz3n9YxylKLlR5SQ/
+md5:$1$abcdefgh$cHJi5PXp/ki/ktXzqlk6I1
+
+sha512:$6$salt$IxDD3jeSOb5eB1CX5LBsqZFVkJdido3OUILO5Ifz5iwMuTS4XMS130MTSuDDl3aCI6WouIL9AjRbLCelDCy.g.
+des:abJnggxhB/yWI
+bcrypt:$2y$05$c4WoMPo3SXsafkva.HHa6uXQZWr7oboPiC2bT/r7q1BB8I2s0BRqC
+plain:{PLAIN}other
+broken:{SSHA}abc
+unknown:abc
+`
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestHtpasswd(c *C) {
+	dir := c.MkDir()
+
+	c.Assert(ioutil.WriteFile(dir+"/htpasswd", []byte(htpasswdTestData), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(dir+"/broken", []byte("user\n"), 0644), IsNil)
+
+	config := &Config{Root: dir}
+	htpasswd, err := config.ReadHtpasswd("htpasswd")
+
+	c.Assert(err, IsNil)
+	c.Assert(htpasswd.Users, HasLen, 11)
+
+	schemes := map[string]string{}
+
+	for _, user := range htpasswd.Users {
+		schemes[user.Name] = user.Scheme
+	}
+
+	c.Assert(schemes, DeepEquals, map[string]string{
+		"plain": HASH_PLAIN, "sha": HASH_SHA, "ssha": HASH_SSHA,
+		"apr1": HASH_APR1, "md5": HASH_MD5_CRYPT, "sha512": HASH_SHA512_CRYPT,
+		"des": HASH_DES_CRYPT, "bcrypt": HASH_BCRYPT, "broken": HASH_SSHA,
+		"unknown": HASH_UNKNOWN,
+	})
+
+	c.Assert(htpasswd.Find("ssha").Hash, Equals, "{SSHA}gVK8WC9YyFT1gMsQHTGCgT3sSv5zYWx0")
+	c.Assert(htpasswd.Find("plain").Line, Equals, 2)
+	c.Assert(htpasswd.Find("nobody"), IsNil)
+
+	for _, name := range []string{"plain", "sha", "ssha", "apr1", "md5"} {
+		ok, err := htpasswd.Verify(name, "secret")
+		c.Assert(err, IsNil, Commentf("User: %s", name))
+		c.Assert(ok, Equals, true, Commentf("User: %s", name))
+
+		ok, err = htpasswd.Verify(name, "Secret")
+		c.Assert(err, IsNil, Commentf("User: %s", name))
+		c.Assert(ok, Equals, false, Commentf("User: %s", name))
+	}
+
+	ok, err := htpasswd.Verify("nobody", "secret")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	_, err = htpasswd.Verify("sha512", "secret")
+	c.Assert(err, NotNil)
+	_, err = htpasswd.Verify("broken", "secret")
+	c.Assert(err, NotNil)
+
+	c.Assert(htpasswd.Problems(), DeepEquals, []string{
+		"Line 2: user plain has weak hash (plain)",
+		"Line 3: user sha has weak hash (sha)",
+		"Line 5: user apr1 has weak hash (apr1)",
+		"Line 6: user md5 has weak hash (md5-crypt)",
+		"Line 9: user des has weak hash (des-crypt)",
+		"Line 10: user bcrypt has hash with scheme unsupported by NGINX (bcrypt)",
+		"Line 11: user plain is already defined on line 2",
+		"Line 13: user unknown has hash with scheme unsupported by NGINX (unknown)",
+	})
+
+	_, err = config.ReadHtpasswd("broken")
+	c.Assert(err, NotNil)
+	_, err = config.ReadHtpasswd("unknown")
+	c.Assert(err, NotNil)
+	_, err = config.ReadHtpasswd("$host.htpasswd")
+	c.Assert(err, NotNil)
+
+	htpasswd = nil
+
+	c.Assert(htpasswd.Find("plain"), IsNil)
+	c.Assert(htpasswd.Problems(), IsNil)
+	c.Assert(md5Crypt("", "verylongsalt", "$1$"), Equals, md5Crypt("", "verylong", "$1$"))
+}
@@ -0,0 +1,384 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Limits contains info about request and connection limits
+type Limits struct {
+	RequestZones    map[string]*LimitReqZone
+	ConnectionZones map[string]*LimitConnZone
+	Requests        []*LimitReq
+	Connections     []*LimitConn
+}
+
+// LimitReqZone contains info about limit_req_zone directive
+type LimitReqZone struct {
+	Name       string
+	Key        string
+	Size       Size
+	Rate       int
+	RatePeriod time.Duration // time.Second for r/s and time.Minute for r/m
+	Sync       bool
+}
+
+// LimitConnZone contains info about limit_conn_zone directive
+type LimitConnZone struct {
+	Name string
+	Key  string
+	Size Size
+	Sync bool
+}
+
+// LimitReq contains info about limit_req directive
+type LimitReq struct {
+	ZoneName string
+	Burst    int
+	NoDelay  bool
+	Delay    int
+	Context  string
+	Zone     *LimitReqZone
+}
+
+// LimitConn contains info about limit_conn directive
+type LimitConn struct {
+	ZoneName string
+	Number   int
+	Context  string
+	Zone     *LimitConnZone
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetLimits returns info about all limit zones and their usages
+func (h *HTTP) GetLimits() (*Limits, error) {
+	if h == nil {
+		return nil, fmt.Errorf("HTTP is nil")
+	}
+
+	limits := &Limits{
+		RequestZones:    make(map[string]*LimitReqZone),
+		ConnectionZones: make(map[string]*LimitConnZone),
+	}
+
+	for _, value := range h.Properties["limit_req_zone"] {
+		zone, err := ParseLimitReqZone(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if limits.RequestZones[zone.Name] != nil {
+			return nil, fmt.Errorf("Request limit zone %s is already defined", zone.Name)
+		}
+
+		limits.RequestZones[zone.Name] = zone
+	}
+
+	for _, value := range h.Properties["limit_conn_zone"] {
+		zone, err := ParseLimitConnZone(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if limits.ConnectionZones[zone.Name] != nil {
+			return nil, fmt.Errorf("Connection limit zone %s is already defined", zone.Name)
+		}
+
+		limits.ConnectionZones[zone.Name] = zone
+	}
+
+	var err error
+
+	h.walk(func(context, name, value string) {
+		if err != nil {
+			return
+		}
+
+		switch name {
+		case "limit_req":
+			var limit *LimitReq
+
+			limit, err = ParseLimitReq(value)
+
+			if err == nil {
+				limit.Context = context
+				limit.Zone = limits.RequestZones[limit.ZoneName]
+				limits.Requests = append(limits.Requests, limit)
+			}
+
+		case "limit_conn":
+			var limit *LimitConn
+
+			limit, err = ParseLimitConn(value)
+
+			if err == nil {
+				limit.Context = context
+				limit.Zone = limits.ConnectionZones[limit.ZoneName]
+				limits.Connections = append(limits.Connections, limit)
+			}
+		}
+
+		if err != nil {
+			err = fmt.Errorf("%s: %v", context, err)
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseLimitReqZone parses limit_req_zone directive value
+func ParseLimitReqZone(data string) (*LimitReqZone, error) {
+	args := parseArgs(data)
+
+	if len(args) < 3 {
+		return nil, fmt.Errorf("Invalid number of limit_req_zone parameters")
+	}
+
+	zone := &LimitReqZone{Key: args[0]}
+
+	for _, arg := range args[1:] {
+		var err error
+
+		switch {
+		case strings.HasPrefix(arg, "zone="):
+			zone.Name, zone.Size, err = parseZoneSpec(arg[5:])
+		case strings.HasPrefix(arg, "rate="):
+			zone.Rate, zone.RatePeriod, err = parseRate(arg[5:])
+		case arg == "sync":
+			zone.Sync = true
+		default:
+			err = fmt.Errorf("Unsupported parameter %s", arg)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid limit_req_zone: %v", err)
+		}
+	}
+
+	switch {
+	case zone.Name == "":
+		return nil, fmt.Errorf("Invalid limit_req_zone: zone is not set")
+	case zone.Rate == 0:
+		return nil, fmt.Errorf("Invalid limit_req_zone: rate is not set")
+	}
+
+	return zone, nil
+}
+
+// ParseLimitConnZone parses limit_conn_zone directive value
+func ParseLimitConnZone(data string) (*LimitConnZone, error) {
+	args := parseArgs(data)
+
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Invalid number of limit_conn_zone parameters")
+	}
+
+	zone := &LimitConnZone{Key: args[0]}
+
+	for _, arg := range args[1:] {
+		var err error
+
+		switch {
+		case strings.HasPrefix(arg, "zone="):
+			zone.Name, zone.Size, err = parseZoneSpec(arg[5:])
+		case arg == "sync":
+			zone.Sync = true
+		default:
+			err = fmt.Errorf("Unsupported parameter %s", arg)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid limit_conn_zone: %v", err)
+		}
+	}
+
+	if zone.Name == "" {
+		return nil, fmt.Errorf("Invalid limit_conn_zone: zone is not set")
+	}
+
+	return zone, nil
+}
+
+// ParseLimitReq parses limit_req directive value
+func ParseLimitReq(data string) (*LimitReq, error) {
+	limit := &LimitReq{}
+
+	for _, arg := range parseArgs(data) {
+		var err error
+
+		switch {
+		case strings.HasPrefix(arg, "zone="):
+			limit.ZoneName = arg[5:]
+		case strings.HasPrefix(arg, "burst="):
+			limit.Burst, err = parseLimitNumber(arg[6:])
+		case strings.HasPrefix(arg, "delay="):
+			limit.Delay, err = parseLimitNumber(arg[6:])
+		case arg == "nodelay":
+			limit.NoDelay = true
+		default:
+			err = fmt.Errorf("Unsupported parameter %s", arg)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid limit_req: %v", err)
+		}
+	}
+
+	if limit.ZoneName == "" {
+		return nil, fmt.Errorf("Invalid limit_req: zone is not set")
+	}
+
+	return limit, nil
+}
+
+// ParseLimitConn parses limit_conn directive value
+func ParseLimitConn(data string) (*LimitConn, error) {
+	args := parseArgs(data)
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("Invalid number of limit_conn parameters")
+	}
+
+	number, err := parseLimitNumber(args[1])
+
+	if err != nil || number == 0 {
+		return nil, fmt.Errorf("Invalid limit_conn: invalid number of connections %s", args[1])
+	}
+
+	return &LimitConn{ZoneName: args[0], Number: number}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// UndefinedZones returns slice with names of zones which are used but not defined
+func (l *Limits) UndefinedZones() []string {
+	var result []string
+
+	if l == nil {
+		return nil
+	}
+
+	for _, limit := range l.Requests {
+		if limit.Zone == nil && !containsString(result, limit.ZoneName) {
+			result = append(result, limit.ZoneName)
+		}
+	}
+
+	for _, limit := range l.Connections {
+		if limit.Zone == nil && !containsString(result, limit.ZoneName) {
+			result = append(result, limit.ZoneName)
+		}
+	}
+
+	return result
+}
+
+// UnusedZones returns slice with names of zones which are defined but not used
+func (l *Limits) UnusedZones() []string {
+	var result []string
+
+	if l == nil {
+		return nil
+	}
+
+	usedReq := make(map[string]bool)
+	usedConn := make(map[string]bool)
+
+	for _, limit := range l.Requests {
+		usedReq[limit.ZoneName] = true
+	}
+
+	for _, limit := range l.Connections {
+		usedConn[limit.ZoneName] = true
+	}
+
+	var reqZones []string
+
+	for name := range l.RequestZones {
+		reqZones = append(reqZones, name)
+	}
+
+	for _, name := range sortStrings(reqZones) {
+		if !usedReq[name] {
+			result = append(result, name)
+		}
+	}
+
+	var connZones []string
+
+	for name := range l.ConnectionZones {
+		connZones = append(connZones, name)
+	}
+
+	for _, name := range sortStrings(connZones) {
+		if !usedConn[name] && !containsString(result, name) {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+// RatePerSecond returns zone rate in requests per second
+func (z *LimitReqZone) RatePerSecond() float64 {
+	if z == nil || z.RatePeriod == 0 {
+		return 0
+	}
+
+	return float64(z.Rate) / z.RatePeriod.Seconds()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseRate parses rate value (like 10r/s or 30r/m)
+func parseRate(data string) (int, time.Duration, error) {
+	var period time.Duration
+
+	switch {
+	case strings.HasSuffix(data, "r/s"):
+		period = time.Second
+	case strings.HasSuffix(data, "r/m"):
+		period = time.Minute
+	default:
+		return 0, 0, fmt.Errorf("Invalid rate %s", data)
+	}
+
+	rate, err := parseLimitNumber(data[:len(data)-3])
+
+	if err != nil || rate == 0 {
+		return 0, 0, fmt.Errorf("Invalid rate %s", data)
+	}
+
+	return rate, period, nil
+}
+
+// parseLimitNumber parses non-negative number
+func parseLimitNumber(data string) (int, error) {
+	num, err := strconv.Atoi(data)
+
+	if err != nil || num < 0 || strings.HasPrefix(data, "+") {
+		return 0, fmt.Errorf("Invalid number %s", data)
+	}
+
+	return num, nil
+}
@@ -0,0 +1,136 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestLimits(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	http := config.HTTP
+	http.Properties["limit_req_zone"] = []string{
+		"$binary_remote_addr zone=perip:10m rate=10r/s",
+		"$server_name zone=perserver:1m rate=30r/m sync",
+	}
+	http.Properties["limit_conn_zone"] = []string{"$binary_remote_addr zone=addr:10m"}
+	http.Properties["limit_conn"] = []string{"addr 100"}
+
+	server := http.FindServer("service.domain.com", "https")
+	server.Properties.Data["limit_req"] = []ConditionalProperty{{-1, "zone=perip burst=20 nodelay"}}
+	server.Locations[1].Properties.Data["limit_req"] = []ConditionalProperty{
+		{-1, "zone=perip burst=5 delay=2"}, {-1, "zone=unknown"},
+	}
+
+	limits, err := http.GetLimits()
+
+	c.Assert(err, IsNil)
+	c.Assert(limits.RequestZones, HasLen, 2)
+	c.Assert(limits.RequestZones["perip"], DeepEquals, &LimitReqZone{
+		Name: "perip", Key: "$binary_remote_addr", Size: 10 * MEGABYTE,
+		Rate: 10, RatePeriod: time.Second,
+	})
+	c.Assert(limits.RequestZones["perserver"].Sync, Equals, true)
+	c.Assert(limits.RequestZones["perserver"].RatePerSecond(), Equals, 0.5)
+	c.Assert(limits.ConnectionZones["addr"].Size, Equals, 10*MEGABYTE)
+
+	c.Assert(limits.Connections, HasLen, 1)
+	c.Assert(limits.Connections[0].Context, Equals, "http")
+	c.Assert(limits.Connections[0].Number, Equals, 100)
+	c.Assert(limits.Connections[0].Zone, Equals, limits.ConnectionZones["addr"])
+
+	c.Assert(limits.Requests, HasLen, 3)
+	c.Assert(limits.Requests[0].Context, Equals, "http/server[2]")
+	c.Assert(limits.Requests[0].Burst, Equals, 20)
+	c.Assert(limits.Requests[0].NoDelay, Equals, true)
+	c.Assert(limits.Requests[0].Zone, Equals, limits.RequestZones["perip"])
+	c.Assert(limits.Requests[1].Context, Equals, "http/server[2]/location[/]")
+	c.Assert(limits.Requests[1].Delay, Equals, 2)
+	c.Assert(limits.Requests[2].Zone, IsNil)
+
+	c.Assert(limits.UndefinedZones(), DeepEquals, []string{"unknown"})
+	c.Assert(limits.UnusedZones(), DeepEquals, []string{"perserver"})
+
+	// Request and connection zones have separate namespaces
+	mixed := &Limits{
+		RequestZones:    map[string]*LimitReqZone{"one": {Name: "one"}},
+		ConnectionZones: map[string]*LimitConnZone{},
+		Connections:     []*LimitConn{{ZoneName: "one"}},
+	}
+
+	c.Assert(mixed.UnusedZones(), DeepEquals, []string{"one"})
+	c.Assert(mixed.UndefinedZones(), DeepEquals, []string{"one"})
+
+	for _, data := range []string{
+		"$binary_remote_addr zone=perip:10m",
+		"$binary_remote_addr rate=1r/s sync",
+		"$binary_remote_addr zone=perip:10m rate=1r/h",
+		"$binary_remote_addr zone=perip:10m rate=0r/s",
+		"$binary_remote_addr zone=perip:10x rate=1r/s",
+		"$binary_remote_addr zone=:10m rate=1r/s",
+		"$binary_remote_addr zone=perip:10m rate=1r/s forever",
+	} {
+		_, err = ParseLimitReqZone(data)
+		c.Assert(err, NotNil, Commentf("Zone: %s", data))
+	}
+
+	for _, data := range []string{"$binary_remote_addr", "$binary_remote_addr sync", "$a zone=a:1m b"} {
+		_, err = ParseLimitConnZone(data)
+		c.Assert(err, NotNil, Commentf("Zone: %s", data))
+	}
+
+	for _, data := range []string{"burst=5", "zone=a burst=-1", "zone=a delay=x", "zone=a fast"} {
+		_, err = ParseLimitReq(data)
+		c.Assert(err, NotNil, Commentf("Limit: %s", data))
+	}
+
+	for _, data := range []string{"addr", "addr 0", "addr +1"} {
+		_, err = ParseLimitConn(data)
+		c.Assert(err, NotNil, Commentf("Limit: %s", data))
+	}
+
+	server.Properties.Data["limit_req"] = []ConditionalProperty{{-1, "burst=1"}}
+	_, err = http.GetLimits()
+	c.Assert(err, NotNil)
+
+	http.Properties["limit_conn"] = []string{"addr"}
+	_, err = http.GetLimits()
+	c.Assert(err, NotNil)
+
+	http.Properties["limit_conn_zone"] = []string{"$a zone=addr:1m", "$b zone=addr:1m"}
+	_, err = http.GetLimits()
+	c.Assert(err, NotNil)
+
+	http.Properties["limit_conn_zone"] = []string{"$a"}
+	_, err = http.GetLimits()
+	c.Assert(err, NotNil)
+
+	http.Properties["limit_req_zone"] = []string{"$a zone=a:1m rate=1r/s", "$b zone=a:1m rate=1r/s"}
+	_, err = http.GetLimits()
+	c.Assert(err, NotNil)
+
+	http.Properties["limit_req_zone"] = []string{"$a"}
+	_, err = http.GetLimits()
+	c.Assert(err, NotNil)
+
+	var nilLimits *Limits
+
+	c.Assert(nilLimits.UndefinedZones(), IsNil)
+	c.Assert(nilLimits.UnusedZones(), IsNil)
+	c.Assert((*LimitReqZone)(nil).RatePerSecond(), Equals, 0.0)
+
+	_, err = (*HTTP)(nil).GetLimits()
+	c.Assert(err, NotNil)
+}
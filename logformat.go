@@ -0,0 +1,665 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	ESCAPE_DEFAULT = "default"
+	ESCAPE_JSON    = "json"
+	ESCAPE_NONE    = "none"
+)
+
+// DEFAULT_LOG_FORMAT is name of predefined log format
+const DEFAULT_LOG_FORMAT = "combined"
+
+// DEFAULT_LOG_BUFFER is default size of buffer used for compressed logs
+const DEFAULT_LOG_BUFFER = 64 * KILOBYTE
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// LogFormat contains info about log format
+type LogFormat struct {
+	Name      string
+	Escape    string
+	Format    string
+	Variables []string
+
+	segments  []logSegment
+	ambiguous bool // Format contains adjacent variables and can't be parsed
+}
+
+// AccessLog contains info about access_log directive
+type AccessLog struct {
+	Path       string
+	FormatName string
+	Format     *LogFormat
+	Buffer     Size
+	Gzip       int // Compression level (0 if compression is disabled)
+	Flush      time.Duration
+	Condition  string // Condition from "if" parameter
+	Syslog     *Syslog
+}
+
+// LogRecord contains parsed log line data (variable name → value)
+type LogRecord map[string]string
+
+// logSegment is part of log format (literal text or variable)
+type logSegment struct {
+	Text       string
+	IsVariable bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// combinedFormat is format of predefined "combined" log format
+var combinedFormat = `$remote_addr - $remote_user [$time_local] ` +
+	`"$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+// matchStatesPool contains buffers for marking failed match states, so they
+// aren't allocated for every parsed line
+var matchStatesPool = sync.Pool{New: func() interface{} { return new([]bool) }}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseLogFormat parses log_format directive value
+func ParseLogFormat(data string) (*LogFormat, error) {
+	args := parseArgs(data)
+
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Log format must have name and format string")
+	}
+
+	format := &LogFormat{Name: args[0], Escape: ESCAPE_DEFAULT}
+	args = args[1:]
+
+	if strings.HasPrefix(args[0], "escape=") {
+		format.Escape = strings.TrimPrefix(args[0], "escape=")
+		args = args[1:]
+
+		switch format.Escape {
+		case ESCAPE_DEFAULT, ESCAPE_JSON, ESCAPE_NONE:
+		default:
+			return nil, fmt.Errorf("Unsupported escaping %s in log format %s", format.Escape, format.Name)
+		}
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Log format %s doesn't have format string", format.Name)
+	}
+
+	format.Format = strings.Join(args, "")
+
+	err := format.compile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return format, nil
+}
+
+// ParseAccessLog parses access_log directive value
+func ParseAccessLog(data string) (*AccessLog, error) {
+	args := parseArgs(data)
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Access log doesn't have path")
+	}
+
+	accessLog := &AccessLog{Path: args[0]}
+
+	if accessLog.Path == "off" {
+		return accessLog, nil
+	}
+
+	if IsSyslog(accessLog.Path) {
+		var err error
+
+		accessLog.Syslog, err = ParseSyslog(accessLog.Path)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accessLog.FormatName = DEFAULT_LOG_FORMAT
+	args = args[1:]
+
+	if len(args) != 0 && !strings.Contains(args[0], "=") && args[0] != "gzip" {
+		accessLog.FormatName = args[0]
+		args = args[1:]
+	}
+
+	for _, arg := range args {
+		err := accessLog.setParameter(arg)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid parameter %s of access log %s: %v", arg, accessLog.Path, err)
+		}
+	}
+
+	if accessLog.Gzip != 0 && accessLog.Buffer == 0 {
+		accessLog.Buffer = DEFAULT_LOG_BUFFER
+	}
+
+	if accessLog.Flush != 0 && accessLog.Buffer == 0 {
+		return nil, fmt.Errorf("Access log %s has flush parameter without buffer", accessLog.Path)
+	}
+
+	return accessLog, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetLogFormats returns all log formats defined in http block (including
+// predefined "combined" format)
+func (h *HTTP) GetLogFormats() (map[string]*LogFormat, error) {
+	result := make(map[string]*LogFormat)
+	result[DEFAULT_LOG_FORMAT], _ = ParseLogFormat(DEFAULT_LOG_FORMAT + " '" + combinedFormat + "'")
+
+	if h == nil {
+		return result, nil
+	}
+
+	for _, v := range h.Properties["log_format"] {
+		format, err := ParseLogFormat(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if result[format.Name] != nil {
+			return nil, fmt.Errorf("Duplicate log format name %s", format.Name)
+		}
+
+		result[format.Name] = format
+	}
+
+	return result, nil
+}
+
+// FindLogFormat returns log format with given name
+func (h *HTTP) FindLogFormat(name string) *LogFormat {
+	formats, err := h.GetLogFormats()
+
+	if err != nil {
+		return nil
+	}
+
+	return formats[name]
+}
+
+// GetAccessLogs returns info about all access_log directives defined in http,
+// server and location blocks with resolved log formats
+func (h *HTTP) GetAccessLogs() ([]*AccessLog, error) {
+	var result []*AccessLog
+
+	if h == nil {
+		return nil, nil
+	}
+
+	formats, err := h.GetLogFormats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := append([]string{}, h.Properties["access_log"]...)
+
+	for _, server := range h.Servers {
+		for _, p := range server.Properties.Data["access_log"] {
+			values = append(values, p.Value)
+		}
+
+		for _, location := range server.Locations {
+			for _, p := range location.Properties.Data["access_log"] {
+				values = append(values, p.Value)
+			}
+		}
+	}
+
+	for _, v := range values {
+		accessLog, err := ParseAccessLog(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if accessLog.Path != "off" {
+			accessLog.Format = formats[accessLog.FormatName]
+
+			if accessLog.Format == nil {
+				return nil, fmt.Errorf("Unknown log format %s used by access log %s", accessLog.FormatName, accessLog.Path)
+			}
+		}
+
+		result = append(result, accessLog)
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Parse parses log line
+func (f *LogFormat) Parse(line string) (LogRecord, error) {
+	if f == nil || len(f.segments) == 0 {
+		return nil, fmt.Errorf("Log format is empty")
+	}
+
+	if f.ambiguous {
+		return nil, fmt.Errorf("Log format %s contains adjacent variables and can't be used for parsing", f.Name)
+	}
+
+	values := make([]string, len(f.segments))
+	failed := getMatchStates((len(f.segments) + 1) * (len(line) + 1))
+	ok := f.match(line, 0, 0, values, *failed)
+
+	matchStatesPool.Put(failed)
+
+	if !ok {
+		return nil, fmt.Errorf("Line doesn't match log format %s", f.Name)
+	}
+
+	record := make(LogRecord)
+
+	for i, segment := range f.segments {
+		if !segment.IsVariable {
+			continue
+		}
+
+		value, err := unescapeLogValue(values[i], f.Escape)
+
+		if err != nil {
+			return nil, err
+		}
+
+		record[segment.Text] = value
+	}
+
+	return record, nil
+}
+
+// setParameter parses and sets access log parameter
+func (l *AccessLog) setParameter(arg string) error {
+	var err error
+
+	name, value := arg, ""
+
+	if index := strings.IndexByte(arg, '='); index != -1 {
+		name, value = arg[:index], arg[index+1:]
+	}
+
+	switch name {
+	case "buffer":
+		l.Buffer, err = ParseSize(value)
+
+		if err == nil && l.Buffer == 0 {
+			err = fmt.Errorf("Buffer size can't be zero")
+		}
+
+	case "gzip":
+		l.Gzip = 1
+
+		if value != "" {
+			l.Gzip, err = strconv.Atoi(value)
+
+			if err != nil || l.Gzip < 1 || l.Gzip > 9 {
+				err = fmt.Errorf("Invalid compression level")
+			}
+		}
+
+	case "flush":
+		l.Flush, err = ParseTimeMsec(value)
+
+	case "if":
+		if value == "" {
+			err = fmt.Errorf("Condition is empty")
+		}
+
+		l.Condition = value
+
+	default:
+		err = fmt.Errorf("Unsupported parameter")
+	}
+
+	return err
+}
+
+// compile splits format to segments
+func (f *LogFormat) compile() error {
+	var literal strings.Builder
+
+	f.segments, f.Variables, f.ambiguous = nil, nil, false
+
+	format := f.Format
+
+	for len(format) != 0 {
+		index := strings.IndexByte(format, '$')
+
+		if index == -1 {
+			literal.WriteString(format)
+			break
+		}
+
+		literal.WriteString(format[:index])
+		name, size := parseVariableName(format[index:])
+
+		if name == "" {
+			literal.WriteByte('$')
+			format = format[index+1:]
+			continue
+		}
+
+		if literal.Len() != 0 {
+			f.segments = append(f.segments, logSegment{Text: literal.String()})
+			literal.Reset()
+		} else if len(f.segments) != 0 {
+			// Values of adjacent variables can't be split unambiguously
+			f.ambiguous = true
+		}
+
+		f.segments = append(f.segments, logSegment{Text: name, IsVariable: true})
+		f.Variables = append(f.Variables, name)
+		format = format[index+size:]
+	}
+
+	if literal.Len() != 0 {
+		f.segments = append(f.segments, logSegment{Text: literal.String()})
+	}
+
+	return nil
+}
+
+// match matches line with format segments starting from given segment and
+// position in line. States which failed to match are marked in failed slice,
+// so every state is checked only once.
+func (f *LogFormat) match(line string, segIndex, pos int, values []string, failed []bool) bool {
+	state := segIndex*(len(line)+1) + pos
+
+	if failed[state] {
+		return false
+	}
+
+	if !f.matchSegment(line, segIndex, pos, values, failed) {
+		failed[state] = true
+		return false
+	}
+
+	return true
+}
+
+// matchSegment matches segment with given index with line starting from
+// given position
+func (f *LogFormat) matchSegment(line string, segIndex, pos int, values []string, failed []bool) bool {
+	if segIndex == len(f.segments) {
+		return pos == len(line)
+	}
+
+	segment := f.segments[segIndex]
+
+	if !segment.IsVariable {
+		if !strings.HasPrefix(line[pos:], segment.Text) {
+			return false
+		}
+
+		return f.match(line, segIndex+1, pos+len(segment.Text), values, failed)
+	}
+
+	// Last variable takes the rest of the line
+	if segIndex+1 == len(f.segments) {
+		values[segIndex] = line[pos:]
+		return true
+	}
+
+	next := f.segments[segIndex+1].Text
+
+	for offset := pos; offset <= len(line); {
+		index := strings.Index(line[offset:], next)
+
+		if index == -1 {
+			return false
+		}
+
+		values[segIndex] = line[pos : offset+index]
+
+		if f.match(line, segIndex+1, offset+index, values, failed) {
+			return true
+		}
+
+		offset += index + 1
+	}
+
+	return false
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getMatchStates returns cleared buffer for match states with given size
+func getMatchStates(size int) *[]bool {
+	states := matchStatesPool.Get().(*[]bool)
+
+	if cap(*states) < size {
+		*states = make([]bool, size)
+		return states
+	}
+
+	*states = (*states)[:size]
+
+	for i := range *states {
+		(*states)[i] = false
+	}
+
+	return states
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns value of variable with given name ("-" is returned as an empty
+// string)
+func (r LogRecord) Get(name string) string {
+	v := r[strings.TrimPrefix(name, "$")]
+
+	if v == "-" {
+		return ""
+	}
+
+	return v
+}
+
+// GetInt returns value of variable with given name as int64
+func (r LogRecord) GetInt(name string) (int64, error) {
+	v := r.Get(name)
+
+	if v == "" {
+		return 0, errEmptyProp
+	}
+
+	return parseInt(v)
+}
+
+// GetFloat returns value of variable with given name as float64
+func (r LogRecord) GetFloat(name string) (float64, error) {
+	v := r.Get(name)
+
+	if v == "" {
+		return 0, errEmptyProp
+	}
+
+	return strconv.ParseFloat(v, 64)
+}
+
+// GetDuration returns value of variable with given name (seconds with
+// milliseconds resolution, like $request_time) as duration
+func (r LogRecord) GetDuration(name string) (time.Duration, error) {
+	v, err := r.GetFloat(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(v * float64(time.Second)), nil
+}
+
+// GetTime returns value of time variable ($time_local, $time_iso8601 or $msec)
+// with given name
+func (r LogRecord) GetTime(name string) (time.Time, error) {
+	name = strings.TrimPrefix(name, "$")
+	v := r.Get(name)
+
+	if v == "" {
+		return time.Time{}, errEmptyProp
+	}
+
+	switch name {
+	case "time_local":
+		return time.Parse("02/Jan/2006:15:04:05 -0700", v)
+	case "time_iso8601":
+		return time.Parse(time.RFC3339, v)
+	case "msec":
+		ts, err := strconv.ParseFloat(v, 64)
+
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Unix(0, int64(ts*float64(time.Second))), nil
+	}
+
+	return time.Time{}, fmt.Errorf("Variable %s is not a time variable", name)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseVariableName parses variable name from the beginning of given string
+// (which must start with "$") and returns name and size of whole reference
+func parseVariableName(data string) (string, int) {
+	if len(data) < 2 || data[0] != '$' {
+		return "", 0
+	}
+
+	if data[1] == '{' {
+		end := strings.IndexByte(data, '}')
+
+		if end == -1 || !isVariableName(data[2:end]) {
+			return "", 0
+		}
+
+		return data[2:end], end + 1
+	}
+
+	// Numeric captures always have only one digit
+	if data[1] >= '0' && data[1] <= '9' {
+		return data[1:2], 2
+	}
+
+	size := 1
+
+	for size < len(data) && isVariableNameChar(data[size]) {
+		size++
+	}
+
+	if size == 1 {
+		return "", 0
+	}
+
+	return data[1:size], size
+}
+
+// isVariableName returns true if given string is valid variable name
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isVariableNameChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isVariableNameChar returns true if given char can be used in variable name
+func isVariableNameChar(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+// unescapeLogValue unescapes variable value written with given escaping
+func unescapeLogValue(value, escape string) (string, error) {
+	if escape == ESCAPE_NONE || !strings.Contains(value, `\`) {
+		return value, nil
+	}
+
+	var buf strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			buf.WriteByte(value[i])
+			continue
+		}
+
+		switch {
+		case escape == ESCAPE_DEFAULT && value[i+1] == 'x' && i+3 < len(value):
+			c, err := strconv.ParseUint(value[i+2:i+4], 16, 8)
+
+			if err != nil {
+				return "", fmt.Errorf("Invalid escape sequence in value %q", value)
+			}
+
+			buf.WriteByte(byte(c))
+			i += 3
+
+		case escape == ESCAPE_JSON && value[i+1] == 'u' && i+5 < len(value):
+			c, err := strconv.ParseUint(value[i+2:i+6], 16, 16)
+
+			if err != nil {
+				return "", fmt.Errorf("Invalid escape sequence in value %q", value)
+			}
+
+			buf.WriteRune(rune(c))
+			i += 5
+
+		case escape == ESCAPE_JSON:
+			switch value[i+1] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'b':
+				buf.WriteByte('\b')
+			case 'f':
+				buf.WriteByte('\f')
+			default:
+				buf.WriteByte(value[i+1])
+			}
+
+			i++
+
+		default:
+			buf.WriteByte('\\')
+		}
+	}
+
+	return buf.String(), nil
+}
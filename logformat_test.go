@@ -0,0 +1,227 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strings"
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestLogFormatParsing(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+	c.Assert(config, NotNil)
+
+	formats, err := config.HTTP.GetLogFormats()
+
+	c.Assert(err, IsNil)
+	c.Assert(formats, HasLen, 7)
+	c.Assert(formats["main"].Escape, Equals, ESCAPE_DEFAULT)
+	c.Assert(formats["json_encoded"].Escape, Equals, ESCAPE_JSON)
+	c.Assert(formats["extended"].Variables, DeepEquals, []string{
+		"request_id", "remote_addr", "time_local", "request", "status",
+		"body_bytes_sent", "http_x_forwarded_for", "http_referer", "host",
+		"request_time", "upstream_response_time", "upstream_addr", "upstream_status",
+	})
+
+	c.Assert(config.HTTP.FindLogFormat("combined"), NotNil)
+	c.Assert(config.HTTP.FindLogFormat("unknown"), IsNil)
+
+	accessLogs, err := config.HTTP.GetAccessLogs()
+
+	c.Assert(err, IsNil)
+	c.Assert(accessLogs, HasLen, 1)
+	c.Assert(accessLogs[0].Path, Equals, "/var/log/webkaos/access.log")
+	c.Assert(accessLogs[0].Format.Name, Equals, "main")
+
+	_, err = ParseLogFormat("test")
+	c.Assert(err, NotNil)
+	_, err = ParseLogFormat("test escape=json")
+	c.Assert(err, NotNil)
+	_, err = ParseLogFormat("test escape=xml '$status'")
+	c.Assert(err, NotNil)
+
+	format, err := ParseLogFormat("test '$status$body_bytes_sent'")
+	c.Assert(err, IsNil)
+	c.Assert(format.Variables, DeepEquals, []string{"status", "body_bytes_sent"})
+	_, err = format.Parse("2001")
+	c.Assert(err, NotNil)
+
+	http := &HTTP{Properties: Properties{"log_format": {"test '$status$body_bytes_sent'"}}}
+	formats, err = http.GetLogFormats()
+	c.Assert(err, IsNil)
+	c.Assert(formats["test"], NotNil)
+
+	http = &HTTP{Properties: Properties{"log_format": {"main '$status'", "main '$status'"}}}
+	_, err = http.GetLogFormats()
+	c.Assert(err, NotNil)
+	_, err = http.GetAccessLogs()
+	c.Assert(err, NotNil)
+
+	http = &HTTP{Properties: Properties{"access_log": {"/var/log/access.log unknown"}}}
+	_, err = http.GetAccessLogs()
+	c.Assert(err, NotNil)
+
+	http = nil
+	c.Assert(http.FindLogFormat("combined"), NotNil)
+}
+
+func (s *NginxSuite) TestAccessLogParsing(c *C) {
+	al, err := ParseAccessLog("/var/log/access.log")
+	c.Assert(err, IsNil)
+	c.Assert(al.FormatName, Equals, "combined")
+
+	al, err = ParseAccessLog("/var/log/access.log main buffer=32k")
+	c.Assert(err, IsNil)
+	c.Assert(al.FormatName, Equals, "main")
+
+	al, err = ParseAccessLog("/var/log/access.log gzip")
+	c.Assert(err, IsNil)
+	c.Assert(al.FormatName, Equals, "combined")
+
+	al, err = ParseAccessLog("off")
+	c.Assert(err, IsNil)
+	c.Assert(al.FormatName, Equals, "")
+
+	_, err = ParseAccessLog("")
+	c.Assert(err, NotNil)
+}
+
+func (s *NginxSuite) TestLogLineParsing(c *C) {
+	format, err := ParseLogFormat(`main '[$request_id] $remote_addr - $remote_user [$time_local] "$request" '
+                  '$status $body_bytes_sent "$http_referer" '
+                  '"$http_user_agent" "${http_x_forwarded_for}" $request_time $msec'`)
+
+	c.Assert(err, IsNil)
+
+	record, err := format.Parse(
+		`[abcd] 192.168.1.1 - - [18/Oct/2020:13:55:36 +0300] "GET /index.html HTTP/1.1" ` +
+			`200 2326 "-" "curl/7.29.0 \x22test\x22" "-" 0.012 1603018536.123`,
+	)
+
+	c.Assert(err, IsNil)
+	c.Assert(record.Get("request_id"), Equals, "abcd")
+	c.Assert(record.Get("$remote_user"), Equals, "")
+	c.Assert(record.Get("request"), Equals, "GET /index.html HTTP/1.1")
+	c.Assert(record.Get("http_user_agent"), Equals, `curl/7.29.0 "test"`)
+
+	status, err := record.GetInt("status")
+	c.Assert(err, IsNil)
+	c.Assert(status, Equals, int64(200))
+
+	dur, err := record.GetDuration("request_time")
+	c.Assert(err, IsNil)
+	c.Assert(dur, Equals, 12*time.Millisecond)
+
+	ts, err := record.GetTime("time_local")
+	c.Assert(err, IsNil)
+	c.Assert(ts.UTC(), Equals, time.Date(2020, 10, 18, 10, 55, 36, 0, time.UTC))
+
+	ts, err = record.GetTime("msec")
+	c.Assert(err, IsNil)
+	c.Assert(ts.Unix(), Equals, int64(1603018536))
+
+	_, err = record.GetTime("status")
+	c.Assert(err, NotNil)
+	_, err = record.GetTime("remote_user")
+	c.Assert(err, NotNil)
+	_, err = record.GetInt("remote_user")
+	c.Assert(err, NotNil)
+	_, err = record.GetDuration("remote_user")
+	c.Assert(err, NotNil)
+
+	_, err = format.Parse(`[abcd] 192.168.1.1`)
+	c.Assert(err, NotNil)
+
+	_, err = format.Parse(`[abcd] 192.168.1.1 - - [18/Oct/2020:13:55:36 +0300] "GET / HTTP/1.1" 200 1 "-" "\xZZ" "-" 0 1`)
+	c.Assert(err, NotNil)
+
+	// Line which doesn't match format must be rejected without exponential
+	// backtracking
+	format, err = ParseLogFormat(`main '$remote_addr "$request" "$http_user_agent" "$host"'`)
+	c.Assert(err, IsNil)
+
+	_, err = format.Parse(`127.0.0.1 "GET / HTTP/1.1" "` + strings.Repeat(`" "`, 500) + `" "domain.com`)
+	c.Assert(err, NotNil)
+
+	// Buffer for match states is reused for shorter lines
+	record, err = format.Parse(`127.0.0.1 "GET / HTTP/1.1" "curl" "domain.com"`)
+	c.Assert(err, IsNil)
+	c.Assert(record.Get("host"), Equals, "domain.com")
+
+	var nilFormat *LogFormat
+	_, err = nilFormat.Parse("test")
+	c.Assert(err, NotNil)
+
+	format, err = ParseLogFormat(`json escape=json '{"uri":"$request_uri","ts":"$time_iso8601","status":$status}'`)
+
+	c.Assert(err, IsNil)
+
+	record, err = format.Parse(`{"uri":"/te\"st\\A\n","ts":"2020-10-18T13:55:36+03:00","status":404}`)
+
+	c.Assert(err, IsNil)
+	c.Assert(record.Get("request_uri"), Equals, "/te\"st\\A\n")
+	c.Assert(record.Get("status"), Equals, "404")
+
+	ts, err = record.GetTime("time_iso8601")
+	c.Assert(err, IsNil)
+	c.Assert(ts.Unix(), Equals, int64(1603018536))
+
+	format, err = ParseLogFormat(`raw escape=none '$host $request [$status]'`)
+
+	c.Assert(err, IsNil)
+
+	record, err = format.Parse(`domain.com GET /\x22 HTTP/1.1 [200]`)
+
+	c.Assert(err, IsNil)
+	c.Assert(record.Get("host"), Equals, "domain.com")
+	c.Assert(record.Get("request"), Equals, `GET /\x22 HTTP/1.1`)
+	c.Assert(record.Get("status"), Equals, "200")
+}
+
+func (s *NginxSuite) TestAccessLogParameters(c *C) {
+	al, err := ParseAccessLog("/var/log/access.log main buffer=32k flush=5s if=$loggable")
+	c.Assert(err, IsNil)
+	c.Assert(al, DeepEquals, &AccessLog{
+		Path:       "/var/log/access.log",
+		FormatName: "main",
+		Buffer:     32 * KILOBYTE,
+		Flush:      5 * time.Second,
+		Condition:  "$loggable",
+	})
+
+	al, err = ParseAccessLog("/var/log/access.log.gz gzip")
+	c.Assert(err, IsNil)
+	c.Assert(al.Gzip, Equals, 1)
+	c.Assert(al.Buffer, Equals, DEFAULT_LOG_BUFFER)
+
+	al, err = ParseAccessLog("/var/log/access.log.gz main gzip=9 buffer=1m")
+	c.Assert(err, IsNil)
+	c.Assert(al.Gzip, Equals, 9)
+	c.Assert(al.Buffer, Equals, MEGABYTE)
+
+	for _, data := range []string{
+		"/var/log/access.log main buffer=0",
+		"/var/log/access.log main buffer=1x",
+		"/var/log/access.log main gzip=10",
+		"/var/log/access.log main gzip=fast",
+		"/var/log/access.log main flush=1s",
+		"/var/log/access.log main buffer=1k flush=abc",
+		"/var/log/access.log main if=",
+		"/var/log/access.log main fast",
+		"/var/log/access.log main level=1",
+	} {
+		_, err = ParseAccessLog(data)
+		c.Assert(err, NotNil, Commentf("Access log: %s", data))
+	}
+}
@@ -0,0 +1,210 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	DEFAULT_ACCESS_LOG = "logs/access.log"
+	DEFAULT_ERROR_LOG  = "logs/error.log"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Logging contains info about effective logs in all contexts
+type Logging struct {
+	Targets  []*LogTargets
+	Problems []string
+}
+
+// LogTargets contains info about effective logs in context
+type LogTargets struct {
+	Context    string
+	AccessLogs []*AccessLog // Empty if access log is disabled
+	ErrorLogs  []*ErrorLog
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetLogging returns effective access and error logs for HTTP block and
+// every server and location
+func (c *Config) GetLogging() (*Logging, error) {
+	if c == nil {
+		return nil, fmt.Errorf("Config is nil")
+	}
+
+	logging := &Logging{}
+	errorLogs, err := getErrorLogs(c.Core["error_log"], nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c.HTTP == nil {
+		logging.Targets = append(logging.Targets, &LogTargets{Context: "main", ErrorLogs: errorLogs})
+		return logging, nil
+	}
+
+	formats, err := c.HTTP.GetLogFormats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	h := c.HTTP
+
+	err = logging.addTargets(
+		"http", h.Properties["access_log"],
+		h.Properties["error_log"], errorLogs, formats,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for serverIndex, server := range h.Servers {
+		serverContext := getServerContext(serverIndex)
+
+		err = logging.addTargets(
+			serverContext, server.getInheritedValues("access_log"),
+			server.getInheritedValues("error_log"), errorLogs, formats,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, location := range server.Locations {
+			err = logging.addTargets(
+				getLocationContext(serverContext, location), location.getInheritedValues("access_log"),
+				location.getInheritedValues("error_log"), errorLogs, formats,
+			)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	h.walk(func(context, name, value string) {
+		if err != nil || name != "access_log" {
+			return
+		}
+
+		var accessLog *AccessLog
+
+		accessLog, err = ParseAccessLog(value)
+
+		if err == nil && accessLog.FormatName != "" && formats[accessLog.FormatName] == nil {
+			logging.Problems = append(logging.Problems, fmt.Sprintf(
+				"Log format %s used in %s is not defined",
+				accessLog.FormatName, context,
+			))
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return logging, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Find returns logs for given context
+func (l *Logging) Find(context string) *LogTargets {
+	if l == nil {
+		return nil
+	}
+
+	for _, targets := range l.Targets {
+		if targets.Context == context {
+			return targets
+		}
+	}
+
+	return nil
+}
+
+// IsOK returns true if there are no problems with logs
+func (l *Logging) IsOK() bool {
+	return l != nil && len(l.Problems) == 0
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// addTargets parses and adds logs for context
+func (l *Logging) addTargets(context string, accessValues, errorValues []string, defaultErrorLogs []*ErrorLog, formats map[string]*LogFormat) error {
+	targets := &LogTargets{Context: context, AccessLogs: []*AccessLog{}}
+
+	if len(accessValues) == 0 {
+		accessValues = []string{DEFAULT_ACCESS_LOG}
+	}
+
+	for _, value := range accessValues {
+		accessLog, err := ParseAccessLog(value)
+
+		if err != nil {
+			return fmt.Errorf("Invalid access log in %s: %v", context, err)
+		}
+
+		// "off" disables all access logs on the same level
+		if accessLog.Path == "off" {
+			targets.AccessLogs = []*AccessLog{}
+			break
+		}
+
+		accessLog.Format = formats[accessLog.FormatName]
+		targets.AccessLogs = append(targets.AccessLogs, accessLog)
+	}
+
+	var err error
+
+	targets.ErrorLogs, err = getErrorLogs(errorValues, defaultErrorLogs)
+
+	if err != nil {
+		return fmt.Errorf("Invalid error log in %s: %v", context, err)
+	}
+
+	l.Targets = append(l.Targets, targets)
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getErrorLogs parses error_log values (if there are no values, default logs
+// will be returned)
+func getErrorLogs(values []string, defaultLogs []*ErrorLog) ([]*ErrorLog, error) {
+	var result []*ErrorLog
+
+	if len(values) == 0 {
+		if defaultLogs != nil {
+			return defaultLogs, nil
+		}
+
+		return []*ErrorLog{{Target: DEFAULT_ERROR_LOG, Level: LOG_LEVEL_ERROR}}, nil
+	}
+
+	for _, value := range values {
+		errorLog, err := ParseErrorLog(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, errorLog)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestLogging(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	logging, err := config.GetLogging()
+
+	c.Assert(err, IsNil)
+	c.Assert(logging.IsOK(), Equals, true)
+	c.Assert(logging.Targets, HasLen, 7)
+
+	http := logging.Find("http")
+
+	c.Assert(http.AccessLogs, HasLen, 1)
+	c.Assert(http.AccessLogs[0].Path, Equals, "/var/log/webkaos/access.log")
+	c.Assert(http.AccessLogs[0].Format.Name, Equals, "main")
+	c.Assert(http.ErrorLogs, DeepEquals, []*ErrorLog{{Target: "/var/log/webkaos/error.log", Level: LOG_LEVEL_WARN}})
+
+	location := logging.Find("http/server[0]/location[/]")
+
+	c.Assert(location.AccessLogs, DeepEquals, http.AccessLogs)
+	c.Assert(location.ErrorLogs, DeepEquals, http.ErrorLogs)
+	c.Assert(logging.Find("http/server[9]"), IsNil)
+
+	server := config.HTTP.Servers[2]
+	server.Properties.Data["access_log"] = []ConditionalProperty{
+		{-1, "/var/log/webkaos/service.log extended buffer=32k"},
+		{-1, "/var/log/webkaos/service.json json"},
+		{0, "/var/log/webkaos/client1.log client"},
+	}
+	server.Properties.Data["error_log"] = []ConditionalProperty{{-1, "/var/log/webkaos/service-error.log info"}}
+	server.Locations[0].Properties.Data["access_log"] = []ConditionalProperty{{-1, "off"}}
+	server.Locations[1].Properties.Data["error_log"] = []ConditionalProperty{{-1, "/dev/null crit"}}
+
+	logging, err = config.GetLogging()
+
+	c.Assert(err, IsNil)
+	c.Assert(logging.IsOK(), Equals, false)
+	c.Assert(logging.Problems, DeepEquals, []string{
+		"Log format json used in http/server[2] is not defined",
+		"Log format client used in http/server[2]/if[0] is not defined",
+	})
+
+	targets := logging.Find("http/server[2]")
+
+	c.Assert(targets.AccessLogs, HasLen, 2)
+	c.Assert(targets.AccessLogs[0].Format.Name, Equals, "extended")
+	c.Assert(targets.AccessLogs[0].Buffer, Equals, 32*KILOBYTE)
+	c.Assert(targets.AccessLogs[1].Format, IsNil)
+	c.Assert(targets.ErrorLogs, DeepEquals, []*ErrorLog{{Target: "/var/log/webkaos/service-error.log", Level: LOG_LEVEL_INFO}})
+
+	targets = logging.Find("http/server[2]/location[= /robots.txt]")
+
+	c.Assert(targets.AccessLogs, HasLen, 0)
+	c.Assert(targets.ErrorLogs[0].Level, Equals, LOG_LEVEL_INFO)
+
+	targets = logging.Find("http/server[2]/location[/]")
+
+	c.Assert(targets.AccessLogs, HasLen, 2)
+	c.Assert(targets.ErrorLogs[0].Target, Equals, "/dev/null")
+
+	config.HTTP.Properties["access_log"] = nil
+	config.Core["error_log"] = nil
+
+	logging, err = config.GetLogging()
+
+	c.Assert(err, IsNil)
+
+	targets = logging.Find("http")
+
+	c.Assert(targets.AccessLogs, HasLen, 1)
+	c.Assert(targets.AccessLogs[0].Path, Equals, DEFAULT_ACCESS_LOG)
+	c.Assert(targets.AccessLogs[0].Format.Name, Equals, DEFAULT_LOG_FORMAT)
+	c.Assert(targets.ErrorLogs, DeepEquals, []*ErrorLog{{Target: DEFAULT_ERROR_LOG, Level: LOG_LEVEL_ERROR}})
+
+	server.Properties.Data["access_log"][2].Value = "/var/log/webkaos/client1.log client gzip=0"
+
+	_, err = config.GetLogging()
+	c.Assert(err, NotNil)
+
+	server.Properties.Data["access_log"][0].Value = "/var/log/webkaos/service.log extended buffer=0"
+
+	_, err = config.GetLogging()
+	c.Assert(err, NotNil)
+
+	server.Properties.Data["access_log"] = nil
+	server.Locations[1].Properties.Data["error_log"][0].Value = "/dev/null fatal"
+
+	_, err = config.GetLogging()
+	c.Assert(err, NotNil)
+
+	config.HTTP.Properties["error_log"] = []string{"/dev/null fatal"}
+
+	_, err = config.GetLogging()
+	c.Assert(err, NotNil)
+
+	config.HTTP.Properties["log_format"] = []string{"main"}
+
+	_, err = config.GetLogging()
+	c.Assert(err, NotNil)
+
+	config.Core["error_log"] = []string{"stderr notice"}
+	config.HTTP = nil
+
+	logging, err = config.GetLogging()
+
+	c.Assert(err, IsNil)
+	c.Assert(logging.Targets, DeepEquals, []*LogTargets{
+		{Context: "main", ErrorLogs: []*ErrorLog{{Target: "stderr", Level: LOG_LEVEL_NOTICE}}},
+	})
+
+	config.Core["error_log"] = []string{""}
+
+	_, err = config.GetLogging()
+	c.Assert(err, NotNil)
+
+	var nilLogging *Logging
+
+	c.Assert(nilLogging.Find("http"), IsNil)
+	c.Assert(nilLogging.IsOK(), Equals, false)
+
+	_, err = (*Config)(nil).GetLogging()
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,229 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MapEntryType is type of map entry
+type MapEntryType uint8
+
+const (
+	MAP_ENTRY_EXACT MapEntryType = iota
+	MAP_ENTRY_WILDCARD
+	MAP_ENTRY_REGEX
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Map contains info about map block
+type Map struct {
+	Source    string
+	Variable  string
+	Default   string
+	Hostnames bool
+	Volatile  bool
+	Entries   []*MapEntry
+	Includes  []string
+	Parent    *HTTP
+}
+
+// MapEntry contains map entry
+type MapEntry struct {
+	Key             string
+	Value           string
+	Type            MapEntryType
+	CaseInsensitive bool
+
+	regex *regexp.Regexp
+	err   error // Regex compilation error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Evaluate returns value mapped to given input value
+func (m *Map) Evaluate(input string) string {
+	if m == nil {
+		return ""
+	}
+
+	key := strings.ToLower(input)
+
+	if m.Hostnames {
+		key = strings.TrimSuffix(key, ".")
+	}
+
+	for _, entry := range m.Entries {
+		if entry.Type == MAP_ENTRY_EXACT && entry.Key == key {
+			return entry.Value
+		}
+	}
+
+	if m.Hostnames {
+		if entry := m.findWildcard(key); entry != nil {
+			return entry.Value
+		}
+	}
+
+	for _, entry := range m.Entries {
+		// Entries with regexes not supported by RE2 are skipped
+		if entry.Type != MAP_ENTRY_REGEX || entry.err != nil {
+			continue
+		}
+
+		groups := entry.regex.FindStringSubmatch(input)
+
+		if groups != nil {
+			return expandCaptures(entry.Value, entry.regex, groups)
+		}
+	}
+
+	return m.Default
+}
+
+// findWildcard tries to find wildcard entry for given host name (entries with
+// wildcard at the beginning have priority over entries with wildcard at the end)
+func (m *Map) findWildcard(host string) *MapEntry {
+	var head, tail *MapEntry
+	var headLen, tailLen int
+
+	for _, entry := range m.Entries {
+		if entry.Type != MAP_ENTRY_WILDCARD {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(entry.Key, "*."):
+			suffix := entry.Key[1:]
+
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) && len(suffix) > headLen {
+				head, headLen = entry, len(suffix)
+			}
+
+		case strings.HasPrefix(entry.Key, "."):
+			if (host == entry.Key[1:] || strings.HasSuffix(host, entry.Key)) && len(entry.Key) > headLen {
+				head, headLen = entry, len(entry.Key)
+			}
+
+		case strings.HasSuffix(entry.Key, ".*"):
+			prefix := entry.Key[:len(entry.Key)-1]
+
+			if strings.HasPrefix(host, prefix) && len(host) > len(prefix) && len(prefix) > tailLen {
+				tail, tailLen = entry, len(prefix)
+			}
+		}
+	}
+
+	if head != nil {
+		return head
+	}
+
+	return tail
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseMapBlock parses map block
+func parseMapBlock(data []string, cursor int, args []string) (int, *Map, error) {
+	if len(args) != 2 {
+		return -1, nil, fmt.Errorf("Map block must have source and target variable")
+	}
+
+	varName, size := parseVariableName(args[1])
+
+	if varName == "" || size != len(args[1]) {
+		return -1, nil, fmt.Errorf("Invalid map target variable %s", args[1])
+	}
+
+	m := &Map{Source: args[0], Variable: varName}
+	dataLen := len(data)
+
+	for {
+		if cursor >= dataLen {
+			break
+		}
+
+		line := data[cursor]
+
+		if isBlockEnd(line) {
+			return cursor + 1, m, nil
+		}
+
+		err := m.parseEntry(parseArgs(cleanData(line)))
+
+		if err != nil {
+			return -1, nil, err
+		}
+
+		cursor++
+	}
+
+	return -1, nil, fmt.Errorf("Can't find block end")
+}
+
+// parseEntry parses map entry
+func (m *Map) parseEntry(args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "hostnames":
+		m.Hostnames = true
+		return nil
+
+	case len(args) == 1 && args[0] == "volatile":
+		m.Volatile = true
+		return nil
+
+	case len(args) == 2 && args[0] == "default":
+		m.Default = args[1]
+		return nil
+
+	case len(args) == 2 && args[0] == "include":
+		m.Includes = append(m.Includes, args[1])
+		return nil
+
+	case len(args) != 2:
+		return fmt.Errorf("Invalid number of parameters in map %s entry", m.Variable)
+	}
+
+	entry := &MapEntry{Key: args[0], Value: args[1]}
+
+	switch {
+	case strings.HasPrefix(entry.Key, "~"):
+		entry.Type = MAP_ENTRY_REGEX
+		entry.Key = strings.TrimPrefix(entry.Key, "~")
+
+		if strings.HasPrefix(entry.Key, "*") {
+			entry.Key, entry.CaseInsensitive = entry.Key[1:], true
+		}
+
+		entry.regex, entry.err = compileRegexp(entry.Key, entry.CaseInsensitive)
+
+	default:
+		entry.Key = strings.ToLower(strings.TrimPrefix(entry.Key, "\\"))
+		entry.CaseInsensitive = true
+
+		if m.Hostnames && isWildcardName(entry.Key) {
+			entry.Type = MAP_ENTRY_WILDCARD
+		}
+	}
+
+	m.Entries = append(m.Entries, entry)
+
+	return nil
+}
+
+// isWildcardName returns true if given host name is wildcard
+func isWildcardName(name string) bool {
+	return strings.HasPrefix(name, "*.") ||
+		strings.HasPrefix(name, ".") ||
+		strings.HasSuffix(name, ".*")
+}
@@ -0,0 +1,100 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestMapParsing(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+	c.Assert(config, NotNil)
+
+	m := config.HTTP.Maps["connection_upgrade"]
+
+	c.Assert(m, NotNil)
+	c.Assert(m.Source, Equals, "$http_upgrade")
+	c.Assert(m.Default, Equals, "upgrade")
+	c.Assert(m.Parent, Equals, config.HTTP)
+	c.Assert(m.Evaluate(""), Equals, "close")
+	c.Assert(m.Evaluate("websocket"), Equals, "upgrade")
+
+	vars := config.HTTP.GetVariables()
+
+	c.Assert(vars.Undefined(), HasLen, 0)
+
+	data := []string{
+		"map $http_host $backend {",
+		"hostnames;",
+		"volatile;",
+		"default      default-backend;",
+		"include      backends.map;",
+		"example.com  exact;",
+		"\\default    escaped;",
+		"*.example.com  head;",
+		"*.api.example.com  api;",
+		".example.org   org;",
+		"www.example.*  tail;",
+		"~^(?<sub>[a-z]+)\\.domain\\.com$  regex-$sub-$1-$unknown;",
+		"~*\\.COM$  icase;",
+		"}",
+	}
+
+	_, m, err = parseMapBlock(data, 1, []string{"$http_host", "$backend"})
+
+	c.Assert(err, IsNil)
+	c.Assert(m.Variable, Equals, "backend")
+	c.Assert(m.Hostnames, Equals, true)
+	c.Assert(m.Volatile, Equals, true)
+	c.Assert(m.Includes, DeepEquals, []string{"backends.map"})
+	c.Assert(m.Entries, HasLen, 8)
+
+	c.Assert(m.Evaluate("Example.COM."), Equals, "exact")
+	c.Assert(m.Evaluate("default"), Equals, "escaped")
+	c.Assert(m.Evaluate("www.example.com"), Equals, "head")
+	c.Assert(m.Evaluate("v1.api.example.com"), Equals, "api")
+	c.Assert(m.Evaluate("example.org"), Equals, "org")
+	c.Assert(m.Evaluate("a.b.example.org"), Equals, "org")
+	c.Assert(m.Evaluate("www.example.net"), Equals, "tail")
+	c.Assert(m.Evaluate("test.domain.com"), Equals, "regex-test-test-$unknown")
+	c.Assert(m.Evaluate("test.other.com"), Equals, "icase")
+	c.Assert(m.Evaluate("unknown.net"), Equals, "default-backend")
+
+	var nilMap *Map
+	c.Assert(nilMap.Evaluate("test"), Equals, "")
+
+	_, _, err = parseMapBlock(data, 1, []string{"$http_host"})
+	c.Assert(err, NotNil)
+	_, _, err = parseMapBlock(data, 1, []string{"$http_host", "backend"})
+	c.Assert(err, NotNil)
+	_, _, err = parseMapBlock([]string{"a b c;"}, 0, []string{"$a", "$b"})
+	c.Assert(err, NotNil)
+
+	// Regexes with PCRE-only syntax don't break map parsing
+	_, m, err = parseMapBlock(
+		[]string{"~^/(?!api/) app;", "~^/(\\w++)$ possessive;", "~^/api/ api;", "}"},
+		0, []string{"$uri", "$backend"},
+	)
+
+	c.Assert(err, IsNil)
+	c.Assert(m.Entries, HasLen, 3)
+	c.Assert(m.Entries[0].err, NotNil)
+	c.Assert(m.Entries[1].err, NotNil)
+	c.Assert(m.Evaluate("/web/"), Equals, "")
+	c.Assert(m.Evaluate("/api/v1"), Equals, "api")
+
+	_, _, err = parseMapBlock([]string{"a b;"}, 0, []string{"$a", "$b"})
+	c.Assert(err, NotNil)
+
+	_, _, err = parseHTTPBlock([]string{"map $a {", "}", "}"}, 0)
+	c.Assert(err, NotNil)
+}
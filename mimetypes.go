@@ -0,0 +1,202 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DEFAULT_MIME_TYPE is default value of default_type directive
+const DEFAULT_MIME_TYPE = "text/plain"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MimeTypes contains info about MIME types defined in types block. Default type
+// is taken from http block only, default_type directives defined in server and
+// location blocks aren't taken into account.
+type MimeTypes struct {
+	Default   string // Value of default_type directive from http block
+	Conflicts []*MimeConflict
+
+	types      map[string][]string // MIME type → extensions
+	extensions map[string]string   // extension → MIME type
+}
+
+// MimeConflict contains info about extension mapped to multiple types
+type MimeConflict struct {
+	Extension string
+	Type      string // Type which is used
+	PrevType  string // Type which was overridden
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReadMimeTypes reads and parses file with types block (like mime.types)
+func ReadMimeTypes(file string) (*MimeTypes, error) {
+	filePath, _ := filepath.Abs(file)
+	data, err := readFile(path.Dir(filePath), filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for cursor, line := range data {
+		if !isBlockStart(line) {
+			continue
+		}
+
+		blockName, _ := getBlockName(line)
+
+		if blockName != "types" {
+			return nil, fmt.Errorf("Unsupported block %s in MIME types file", blockName)
+		}
+
+		_, types, err := parseTypesBlock(data, cursor+1, nil, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return getMimeTypesWithDefault(types, ""), nil
+	}
+
+	return nil, fmt.Errorf("File %s doesn't contain types block", file)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// TypeByExtension returns MIME type for given extension (default type will be
+// returned if there is no type for extension)
+func (t *MimeTypes) TypeByExtension(ext string) string {
+	if t == nil {
+		return ""
+	}
+
+	mimeType, ok := t.extensions[strings.ToLower(strings.TrimPrefix(ext, "."))]
+
+	if !ok {
+		return t.Default
+	}
+
+	return mimeType
+}
+
+// ExtensionsByType returns slice with extensions for given MIME type
+func (t *MimeTypes) ExtensionsByType(mimeType string) []string {
+	if t == nil || len(t.types[mimeType]) == 0 {
+		return nil
+	}
+
+	return append([]string{}, t.types[mimeType]...)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// add adds MIME type with given extensions (extension defined later overrides
+// previous definition same as in NGINX)
+func (t *MimeTypes) add(mimeType string, exts []string) {
+	for _, ext := range exts {
+		ext = strings.ToLower(ext)
+		prevType, ok := t.extensions[ext]
+
+		if ok {
+			if prevType == mimeType {
+				continue
+			}
+
+			t.Conflicts = append(t.Conflicts, &MimeConflict{ext, mimeType, prevType})
+			t.types[prevType] = removeString(t.types[prevType], ext)
+		}
+
+		t.extensions[ext] = mimeType
+		t.types[mimeType] = append(t.types[mimeType], ext)
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseTypesBlock parses types block (types are added to given MIME types if
+// it is not nil, raw block properties are added to props if it is not nil)
+func parseTypesBlock(data []string, cursor int, types *MimeTypes, props Properties) (int, *MimeTypes, error) {
+	if types == nil {
+		types = newMimeTypes()
+	}
+
+	dataLen := len(data)
+
+	for {
+		if cursor >= dataLen {
+			break
+		}
+
+		line := data[cursor]
+
+		if isBlockEnd(line) {
+			return cursor + 1, types, nil
+		}
+
+		args := parseArgs(cleanData(line))
+
+		if len(args) < 2 {
+			return -1, nil, fmt.Errorf("MIME type %s doesn't have extensions", getSafe(args, 0))
+		}
+
+		types.add(args[0], args[1:])
+
+		if props != nil {
+			propName, propValue := parseProperty(line)
+			props[propName] = append(props[propName], propValue)
+		}
+
+		cursor++
+	}
+
+	return -1, nil, fmt.Errorf("Can't find block end")
+}
+
+// newMimeTypes creates new empty MIME types struct
+func newMimeTypes() *MimeTypes {
+	return &MimeTypes{
+		types:      make(map[string][]string),
+		extensions: make(map[string]string),
+	}
+}
+
+// getMimeTypesWithDefault sets default type for MIME types (empty MIME types
+// will be created if types is nil)
+func getMimeTypesWithDefault(types *MimeTypes, defaultType string) *MimeTypes {
+	if types == nil {
+		types = newMimeTypes()
+	}
+
+	types.Default = defaultType
+
+	if types.Default == "" {
+		types.Default = DEFAULT_MIME_TYPE
+	}
+
+	return types
+}
+
+// removeString removes all occurrences of value from slice
+func removeString(data []string, value string) []string {
+	var result []string
+
+	for _, v := range data {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,70 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestMimeTypes(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	types := config.HTTP.MimeTypes
+
+	c.Assert(types, NotNil)
+	c.Assert(types.Default, Equals, "application/octet-stream")
+	c.Assert(types.Conflicts, IsNil)
+	c.Assert(types.TypeByExtension("html"), Equals, "text/html")
+	c.Assert(types.TypeByExtension(".JPG"), Equals, "image/jpeg")
+	c.Assert(types.TypeByExtension("xng"), Equals, "image/svg+xml")
+	c.Assert(types.TypeByExtension("unknown"), Equals, "application/octet-stream")
+	c.Assert(types.ExtensionsByType("image/svg+xml"), DeepEquals, []string{"svg", "svgz", "xng"})
+	c.Assert(types.ExtensionsByType("text/unknown"), IsNil)
+	c.Assert(config.HTTP.Types["image/svg+xml"], DeepEquals, []string{"svg svgz xng"})
+
+	types, err = ReadMimeTypes("testdata/mime.types")
+
+	c.Assert(err, IsNil)
+	c.Assert(types.Default, Equals, DEFAULT_MIME_TYPE)
+	c.Assert(types.TypeByExtension("css"), Equals, "text/css")
+
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(dir+"/dup.types", []byte("types {\n  text/xml xml rss;\n  application/rss+xml RSS;\n  text/xml xml;\n}\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(dir+"/empty.types", []byte("default_type text/html;\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(dir+"/block.types", []byte("server {\n}\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(dir+"/broken.types", []byte("types {\n  text/xml;\n}\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(dir+"/unclosed.types", []byte("types {\n  text/xml xml;\n"), 0644), IsNil)
+
+	types, err = ReadMimeTypes(dir + "/dup.types")
+
+	c.Assert(err, IsNil)
+	c.Assert(types.TypeByExtension("rss"), Equals, "application/rss+xml")
+	c.Assert(types.ExtensionsByType("text/xml"), DeepEquals, []string{"xml"})
+	c.Assert(types.Conflicts, DeepEquals, []*MimeConflict{{"rss", "application/rss+xml", "text/xml"}})
+
+	for _, file := range []string{"empty", "block", "broken", "unclosed", "unknown"} {
+		_, err = ReadMimeTypes(dir + "/" + file + ".types")
+		c.Assert(err, NotNil, Commentf("File: %s", file))
+	}
+
+	http := &HTTP{}
+	http.MimeTypes = getMimeTypesWithDefault(nil, "")
+
+	c.Assert(http.MimeTypes.TypeByExtension("html"), Equals, DEFAULT_MIME_TYPE)
+
+	types = nil
+
+	c.Assert(types.TypeByExtension("html"), Equals, "")
+	c.Assert(types.ExtensionsByType("text/html"), IsNil)
+}
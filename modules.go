@@ -0,0 +1,199 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Module contains info about dynamic module loaded by load_module directive
+type Module struct {
+	Name string // Module name derived from file name (e.g. ngx_http_geoip_module)
+	File string // Path from load_module directive
+	Path string // Absolute path to module file
+}
+
+// ModuleUsage contains info about directive provided by dynamic module
+type ModuleUsage struct {
+	Module    string
+	Directive string
+	Context   string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// httpModuleDirectives contains HTTP directives provided by dynamic modules
+var httpModuleDirectives = map[string]string{
+	"brotli":                    "ngx_http_brotli_filter_module",
+	"brotli_buffers":            "ngx_http_brotli_filter_module",
+	"brotli_comp_level":         "ngx_http_brotli_filter_module",
+	"brotli_min_length":         "ngx_http_brotli_filter_module",
+	"brotli_types":              "ngx_http_brotli_filter_module",
+	"brotli_window":             "ngx_http_brotli_filter_module",
+	"brotli_static":             "ngx_http_brotli_static_module",
+	"geoip_city":                "ngx_http_geoip_module",
+	"geoip_country":             "ngx_http_geoip_module",
+	"geoip_org":                 "ngx_http_geoip_module",
+	"geoip_proxy":               "ngx_http_geoip_module",
+	"geoip_proxy_recursive":     "ngx_http_geoip_module",
+	"image_filter":              "ngx_http_image_filter_module",
+	"image_filter_buffer":       "ngx_http_image_filter_module",
+	"image_filter_interlace":    "ngx_http_image_filter_module",
+	"image_filter_jpeg_quality": "ngx_http_image_filter_module",
+	"image_filter_sharpen":      "ngx_http_image_filter_module",
+	"image_filter_transparency": "ngx_http_image_filter_module",
+	"image_filter_webp_quality": "ngx_http_image_filter_module",
+	"js_content":                "ngx_http_js_module",
+	"js_import":                 "ngx_http_js_module",
+	"js_include":                "ngx_http_js_module",
+	"js_path":                   "ngx_http_js_module",
+	"js_set":                    "ngx_http_js_module",
+	"more_clear_headers":        "ngx_http_headers_more_filter_module",
+	"more_clear_input_headers":  "ngx_http_headers_more_filter_module",
+	"more_set_headers":          "ngx_http_headers_more_filter_module",
+	"more_set_input_headers":    "ngx_http_headers_more_filter_module",
+	"perl":                      "ngx_http_perl_module",
+	"perl_modules":              "ngx_http_perl_module",
+	"perl_require":              "ngx_http_perl_module",
+	"perl_set":                  "ngx_http_perl_module",
+	"xml_entities":              "ngx_http_xslt_filter_module",
+	"xslt_last_modified":        "ngx_http_xslt_filter_module",
+	"xslt_param":                "ngx_http_xslt_filter_module",
+	"xslt_string_param":         "ngx_http_xslt_filter_module",
+	"xslt_stylesheet":           "ngx_http_xslt_filter_module",
+	"xslt_types":                "ngx_http_xslt_filter_module",
+}
+
+// streamModuleDirectives contains stream directives provided by dynamic modules
+var streamModuleDirectives = map[string]string{
+	"geoip_city":    "ngx_stream_geoip_module",
+	"geoip_country": "ngx_stream_geoip_module",
+	"geoip_org":     "ngx_stream_geoip_module",
+	"js_access":     "ngx_stream_js_module",
+	"js_filter":     "ngx_stream_js_module",
+	"js_import":     "ngx_stream_js_module",
+	"js_include":    "ngx_stream_js_module",
+	"js_path":       "ngx_stream_js_module",
+	"js_preread":    "ngx_stream_js_module",
+	"js_set":        "ngx_stream_js_module",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetModules returns dynamic modules loaded by load_module directives. Relative
+// paths are resolved against given NGINX prefix (if prefix is empty, config
+// root directory is used).
+func (c *Config) GetModules(prefix string) []*Module {
+	var result []*Module
+
+	if c == nil {
+		return nil
+	}
+
+	if prefix == "" {
+		prefix = c.Root
+	}
+
+	for _, file := range c.Core["load_module"] {
+		file = unquote(file)
+		name := strings.TrimSuffix(filepath.Base(file), ".so")
+
+		result = append(result, &Module{
+			Name: name,
+			File: file,
+			Path: getAbsPath(prefix, file),
+		})
+	}
+
+	return result
+}
+
+// FindModule returns loaded dynamic module with given name (see GetModules
+// for info about prefix)
+func (c *Config) FindModule(name, prefix string) *Module {
+	for _, module := range c.GetModules(prefix) {
+		if module.Name == name {
+			return module
+		}
+	}
+
+	return nil
+}
+
+// GetRequiredModules returns directives provided by dynamic modules. Only
+// directives from http block (with servers and locations) and top-level
+// directives from stream block are checked. Mail directives and nested stream
+// blocks are not supported.
+func (c *Config) GetRequiredModules() []*ModuleUsage {
+	var result []*ModuleUsage
+
+	if c == nil {
+		return nil
+	}
+
+	var names []string
+
+	for name := range c.Stream {
+		names = append(names, name)
+	}
+
+	for _, name := range sortStrings(names) {
+		if streamModuleDirectives[name] != "" {
+			result = append(result, &ModuleUsage{streamModuleDirectives[name], name, "stream"})
+		}
+	}
+
+	c.HTTP.walk(func(context, name, value string) {
+		if httpModuleDirectives[name] != "" {
+			result = append(result, &ModuleUsage{httpModuleDirectives[name], name, context})
+		}
+	})
+
+	return result
+}
+
+// GetMissingModules returns directives provided by dynamic modules which
+// are not loaded
+func (c *Config) GetMissingModules() []*ModuleUsage {
+	var result []*ModuleUsage
+
+	loaded := make(map[string]bool)
+
+	for _, module := range c.GetModules("") {
+		loaded[module.Name] = true
+	}
+
+	for _, usage := range c.GetRequiredModules() {
+		if !loaded[usage.Module] {
+			result = append(result, usage)
+		}
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Exists returns true if module file exists (if file system is nil, OS file
+// system will be used)
+func (m *Module) Exists(fs FileSystem) bool {
+	if m == nil {
+		return false
+	}
+
+	if fs == nil {
+		fs = osFileSystem{}
+	}
+
+	info, err := fs.Stat(m.Path)
+
+	return err == nil && info.Mode().IsRegular()
+}
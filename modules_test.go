@@ -0,0 +1,83 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"os"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestModules(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	modules := config.GetModules("/etc/webkaos")
+
+	c.Assert(modules, DeepEquals, []*Module{
+		{
+			"ngx_http_brotli_filter_module",
+			"modules/ngx_http_brotli_filter_module.so",
+			"/etc/webkaos/modules/ngx_http_brotli_filter_module.so",
+		},
+		{
+			"ngx_http_brotli_static_module",
+			"modules/ngx_http_brotli_static_module.so",
+			"/etc/webkaos/modules/ngx_http_brotli_static_module.so",
+		},
+	})
+
+	c.Assert(config.FindModule("ngx_http_brotli_static_module", "/etc/webkaos"), DeepEquals, modules[1])
+	c.Assert(config.FindModule("ngx_http_geoip_module", ""), IsNil)
+	c.Assert(config.GetMissingModules(), IsNil)
+
+	config.Core["load_module"] = config.Core["load_module"][:1]
+	config.Core["load_module"] = append(config.Core["load_module"], "/usr/lib64/webkaos/modules/ngx_stream_js_module.so")
+	config.Stream = Properties{"js_import": {"main.js"}, "proxy_pass": {"backend"}}
+	config.HTTP.Properties["brotli"] = []string{"on"}
+	config.HTTP.Servers[0].Locations[0].Properties.Data["brotli_static"] = []ConditionalProperty{{-1, "on"}}
+	config.HTTP.Servers[0].Properties.Data["geoip_country"] = []ConditionalProperty{{-1, "/usr/share/GeoIP/GeoIP.dat"}}
+
+	c.Assert(config.GetRequiredModules(), DeepEquals, []*ModuleUsage{
+		{"ngx_stream_js_module", "js_import", "stream"},
+		{"ngx_http_brotli_filter_module", "brotli", "http"},
+		{"ngx_http_geoip_module", "geoip_country", "http/server[0]"},
+		{"ngx_http_brotli_static_module", "brotli_static", "http/server[0]/location[/]"},
+	})
+
+	c.Assert(config.GetMissingModules(), DeepEquals, []*ModuleUsage{
+		{"ngx_http_geoip_module", "geoip_country", "http/server[0]"},
+		{"ngx_http_brotli_static_module", "brotli_static", "http/server[0]/location[/]"},
+	})
+
+	modules = config.GetModules("/etc/webkaos")
+	fs := StubFileSystem{
+		"/etc/webkaos/modules/ngx_http_brotli_filter_module.so": 0755,
+		"/usr/lib64/webkaos/modules":                            os.ModeDir | 0755,
+	}
+
+	c.Assert(modules[0].Exists(fs), Equals, true)
+	c.Assert(modules[1].Path, Equals, "/usr/lib64/webkaos/modules/ngx_stream_js_module.so")
+	c.Assert(modules[1].Exists(fs), Equals, false)
+
+	config.Root = "/etc/nginx"
+	c.Assert(config.GetModules("")[0].Path, Equals, "/etc/nginx/modules/ngx_http_brotli_filter_module.so")
+	c.Assert((&Module{Path: "/usr/lib64/webkaos/modules"}).Exists(fs), Equals, false)
+	c.Assert((&Module{Path: "/unknown/module.so"}).Exists(nil), Equals, false)
+	c.Assert((*Module)(nil).Exists(fs), Equals, false)
+
+	var nilConfig *Config
+
+	c.Assert(nilConfig.GetModules(""), IsNil)
+	c.Assert(nilConfig.FindModule("ngx_http_geoip_module", ""), IsNil)
+	c.Assert(nilConfig.GetRequiredModules(), IsNil)
+	c.Assert(nilConfig.GetMissingModules(), IsNil)
+}
@@ -37,23 +37,32 @@ type Config struct {
 type HTTP struct {
 	Properties Properties
 	Types      Properties
+	MimeTypes  *MimeTypes
+	Access     []*AccessRule // allow/deny rules in order of definition
 	Servers    []*Server
 	Upstreams  map[string]*Upstream
+	Maps       map[string]*Map
+	Geos       map[string]*Geo
+	Splits     map[string]*SplitClients
 }
 
 // Server contains server part of config
 type Server struct {
-	Properties *ConditionalProperties
-	Locations  []*Location
-	Parent     *HTTP
+	Properties   *ConditionalProperties
+	Locations    []*Location
+	RewriteChain []RewriteDirective
+	Access       []*AccessRule // allow/deny rules in order of definition
+	Parent       *HTTP
 }
 
 // Location contains location part of config
 type Location struct {
-	Modifier   string
-	URI        string
-	Properties *ConditionalProperties
-	Parent     *Server
+	Modifier     string
+	URI          string
+	Properties   *ConditionalProperties
+	RewriteChain []RewriteDirective
+	Access       []*AccessRule // allow/deny rules in order of definition
+	Parent       *Server
 }
 
 // ConditionalProperties contains properties with conditions
@@ -68,8 +77,21 @@ type ConditionalProperty struct {
 	Value       string
 }
 
+// RewriteDirective contains rewrite module directive (if, rewrite, return,
+// break or set) in order of definition
+type RewriteDirective struct {
+	ConditionID int
+	Name        string
+	Value       string
+	Rewrite     *Rewrite // Parsed value of rewrite directive
+	Return      *Return  // Parsed value of return directive
+
+	err error // Parsing error
+}
+
 // Upstream contains info about upstream
 type Upstream struct {
+	Name       string
 	Properties Properties
 	Parent     *HTTP
 }
@@ -316,7 +338,14 @@ func parseSimpleBlock(data []string, cursor int) (int, Properties, error) {
 func parseHTTPBlock(data []string, cursor int) (int, *HTTP, error) {
 	var err error
 
-	http := &HTTP{Properties: make(Properties), Upstreams: make(map[string]*Upstream)}
+	http := &HTTP{
+		Properties: make(Properties),
+		Upstreams:  make(map[string]*Upstream),
+		Maps:       make(map[string]*Map),
+		Geos:       make(map[string]*Geo),
+		Splits:     make(map[string]*SplitClients),
+	}
+
 	dataLen := len(data)
 
 	for {
@@ -327,6 +356,7 @@ func parseHTTPBlock(data []string, cursor int) (int, *HTTP, error) {
 		line := data[cursor]
 
 		if isBlockEnd(line) {
+			http.MimeTypes = getMimeTypesWithDefault(http.MimeTypes, http.Properties.Get("default_type"))
 			return cursor + 1, http, nil
 		}
 
@@ -335,15 +365,13 @@ func parseHTTPBlock(data []string, cursor int) (int, *HTTP, error) {
 
 			switch blockName {
 			case "types":
-				var types Properties
-				cursor, types, err = parseSimpleBlock(data, cursor+1)
+				http.Types = make(Properties)
+				cursor, http.MimeTypes, err = parseTypesBlock(data, cursor+1, http.MimeTypes, http.Types)
 
 				if err != nil {
 					return -1, nil, err
 				}
 
-				http.Types = types
-
 				continue
 
 			case "server":
@@ -373,7 +401,46 @@ func parseHTTPBlock(data []string, cursor int) (int, *HTTP, error) {
 					return -1, nil, err
 				}
 
-				http.Upstreams[upstreamName] = &Upstream{props, http}
+				http.Upstreams[upstreamName] = &Upstream{upstreamName, props, http}
+
+				continue
+
+			case "map":
+				var m *Map
+				cursor, m, err = parseMapBlock(data, cursor+1, getBlockArgs(line))
+
+				if err != nil {
+					return -1, nil, err
+				}
+
+				m.Parent = http
+				http.Maps[m.Variable] = m
+
+				continue
+
+			case "geo":
+				var geo *Geo
+				cursor, geo, err = parseGeoBlock(data, cursor+1, getBlockArgs(line))
+
+				if err != nil {
+					return -1, nil, err
+				}
+
+				geo.Parent = http
+				http.Geos[geo.Variable] = geo
+
+				continue
+
+			case "split_clients":
+				var split *SplitClients
+				cursor, split, err = parseSplitClientsBlock(data, cursor+1, getBlockArgs(line))
+
+				if err != nil {
+					return -1, nil, err
+				}
+
+				split.Parent = http
+				http.Splits[split.Variable] = split
 
 				continue
 
@@ -384,6 +451,7 @@ func parseHTTPBlock(data []string, cursor int) (int, *HTTP, error) {
 
 		propName, propValue := parseProperty(line)
 		http.Properties[propName] = append(http.Properties[propName], propValue)
+		http.Access = appendAccessDirective(http.Access, propName, propValue)
 
 		cursor++
 	}
@@ -417,7 +485,7 @@ func parseServerBlock(data []string, cursor int) (int, *Server, error) {
 
 			switch blockName {
 			case "if":
-				cursor, err = parseIfBlock(data, cursor+1, server.Properties)
+				cursor, err = parseIfBlock(data, cursor+1, server.Properties, &server.RewriteChain)
 
 				if err != nil {
 					return -1, nil, err
@@ -446,6 +514,8 @@ func parseServerBlock(data []string, cursor int) (int, *Server, error) {
 
 		propName, propValue := parseProperty(line)
 		server.Properties.Data[propName] = append(server.Properties.Data[propName], ConditionalProperty{-1, propValue})
+		server.RewriteChain = appendRewriteDirective(server.RewriteChain, -1, propName, propValue)
+		server.Access = appendAccessDirective(server.Access, propName, propValue)
 
 		cursor++
 	}
@@ -479,7 +549,7 @@ func parseLocationBlock(data []string, cursor int) (int, *Location, error) {
 
 			switch blockName {
 			case "if":
-				cursor, err = parseIfBlock(data, cursor+1, location.Properties)
+				cursor, err = parseIfBlock(data, cursor+1, location.Properties, &location.RewriteChain)
 
 				if err != nil {
 					return -1, nil, err
@@ -494,6 +564,8 @@ func parseLocationBlock(data []string, cursor int) (int, *Location, error) {
 
 		propName, propValue := parseProperty(line)
 		location.Properties.Data[propName] = append(location.Properties.Data[propName], ConditionalProperty{-1, propValue})
+		location.RewriteChain = appendRewriteDirective(location.RewriteChain, -1, propName, propValue)
+		location.Access = appendAccessDirective(location.Access, propName, propValue)
 
 		cursor++
 	}
@@ -502,13 +574,14 @@ func parseLocationBlock(data []string, cursor int) (int, *Location, error) {
 }
 
 // parseIfBlock parses condition block
-func parseIfBlock(data []string, cursor int, props *ConditionalProperties) (int, error) {
+func parseIfBlock(data []string, cursor int, props *ConditionalProperties, chain *[]RewriteDirective) (int, error) {
 	dataLen := len(data)
 
 	condition := getCondition(data[cursor-1])
 	conditionID := len(props.Conditions)
 
 	props.Conditions = append(props.Conditions, condition)
+	*chain = append(*chain, RewriteDirective{ConditionID: conditionID, Name: "if", Value: condition})
 
 	for {
 		if cursor >= dataLen {
@@ -523,6 +596,7 @@ func parseIfBlock(data []string, cursor int, props *ConditionalProperties) (int,
 
 		propName, propValue := parseProperty(line)
 		props.Data[propName] = append(props.Data[propName], ConditionalProperty{conditionID, propValue})
+		*chain = appendRewriteDirective(*chain, conditionID, propName, propValue)
 
 		cursor++
 	}
@@ -530,6 +604,42 @@ func parseIfBlock(data []string, cursor int, props *ConditionalProperties) (int,
 	return -1, fmt.Errorf("Can't find block end")
 }
 
+// appendRewriteDirective appends property to rewrite chain if property is
+// rewrite module directive. Parsing errors don't break config reading (regex
+// can use PCRE-only syntax), they are returned on chain evaluation.
+func appendRewriteDirective(chain []RewriteDirective, conditionID int, name, value string) []RewriteDirective {
+	directive := RewriteDirective{ConditionID: conditionID, Name: name, Value: value}
+
+	switch name {
+	case "rewrite":
+		directive.Rewrite, directive.err = ParseRewrite(value)
+	case "return":
+		directive.Return, directive.err = ParseReturn(value)
+	case "break", "set":
+		// no parsing required
+	default:
+		return chain
+	}
+
+	return append(chain, directive)
+}
+
+// appendAccessDirective appends property to access rules if property is
+// allow or deny directive (parsing errors are returned on policy evaluation)
+func appendAccessDirective(rules []*AccessRule, name, value string) []*AccessRule {
+	if name != "allow" && name != "deny" {
+		return rules
+	}
+
+	rule, err := ParseAccessRule(name, value)
+
+	if err != nil {
+		rule = &AccessRule{Allow: name == "allow", err: err}
+	}
+
+	return append(rules, rule)
+}
+
 // parseProperty parses property and returns name and value
 func parseProperty(data string) (string, string) {
 	data = cleanData(data)
@@ -543,6 +653,69 @@ func parseProperty(data string) (string, string) {
 	return data[:firstSpaceIndex], data[firstSpaceIndex+1:]
 }
 
+// parseArgs splits property value to arguments with respect to quotes
+func parseArgs(data string) []string {
+	var result []string
+	var buf strings.Builder
+	var quote rune
+	var inArg bool
+
+	data = strings.TrimSpace(data)
+
+	for i := 0; i < len(data); i++ {
+		r := rune(data[i])
+
+		switch {
+		case r == '\\' && i+1 < len(data):
+			inArg = true
+			i++
+
+			switch data[i] {
+			case '"', '\'', '\\':
+				buf.WriteByte(data[i])
+			case 't':
+				buf.WriteByte('\t')
+			case 'r':
+				buf.WriteByte('\r')
+			case 'n':
+				buf.WriteByte('\n')
+			default:
+				buf.WriteByte('\\')
+				buf.WriteByte(data[i])
+			}
+
+		case quote != 0 && r == quote:
+			quote = 0
+			result = append(result, buf.String())
+			buf.Reset()
+			inArg = false
+
+		case quote != 0:
+			buf.WriteByte(data[i])
+
+		case (r == '"' || r == '\'') && !inArg:
+			quote, inArg = r, true
+
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				result = append(result, buf.String())
+				buf.Reset()
+				inArg = false
+			}
+
+		default:
+			inArg = true
+			buf.WriteByte(data[i])
+		}
+	}
+
+	if inArg {
+		result = append(result, buf.String())
+	}
+
+	return result
+}
+
 // parseLocationArgs parses location args
 func parseLocationArgs(data []string) (string, string) {
 	switch len(data) {
@@ -558,7 +731,11 @@ func getCondition(data string) string {
 	cStart := strings.Index(data, "(")
 	cEnd := strings.LastIndex(data, ")")
 
-	return data[cStart+1 : cEnd-1]
+	if cStart == -1 || cEnd < cStart {
+		return ""
+	}
+
+	return strings.TrimSpace(data[cStart+1 : cEnd])
 }
 
 // getBlockName extracts block name
@@ -575,6 +752,20 @@ func getBlockName(data string) (string, []string) {
 	return dataSlice[0], dataSlice[1:]
 }
 
+// getBlockArgs extracts block arguments with respect to quotes
+func getBlockArgs(data string) []string {
+	data = cleanData(data)
+	data = strings.TrimRight(data, " {")
+
+	args := parseArgs(data)
+
+	if len(args) < 2 {
+		return nil
+	}
+
+	return args[1:]
+}
+
 // isFullLine returns true if given line terminated by symbol ;
 func isFullLine(data string) bool {
 	return strings.Contains(data, ";")
@@ -98,16 +98,50 @@ func (s *NginxSuite) TestAux(c *C) {
 
 	c.Assert(cleanData("  location = '{' { # TEST"), Equals, "location = '{' {")
 	c.Assert(cleanData("resolver_timeout           10s;"), Equals, "resolver_timeout 10s")
+
+	c.Assert(parseArgs(""), IsNil)
+	c.Assert(parseArgs(`Host   $host`), DeepEquals, []string{"Host", "$host"})
+	c.Assert(parseArgs(`"" close`), DeepEquals, []string{"", "close"})
+	c.Assert(parseArgs(`'a b' "c \"d\"" e\ f`), DeepEquals, []string{"a b", `c "d"`, `e\ f`})
+	c.Assert(parseArgs(`"MSIE [1-6]\." \t`), DeepEquals, []string{`MSIE [1-6]\.`, "\t"})
 }
 
 func (s *NginxSuite) TestIfBlockParser(c *C) {
 	data := []string{"if ($k == 1) {", "return 100;"}
 	props := &ConditionalProperties{Data: make(map[string][]ConditionalProperty)}
+	chain := []RewriteDirective{}
 
-	_, err := parseIfBlock(data, 1, props)
+	_, err := parseIfBlock(data, 1, props, &chain)
 	c.Assert(err, NotNil)
 }
 
+func (s *NginxSuite) TestRewriteChainParser(c *C) {
+	data := []string{"rewrite ^/(.*)$ /$1 last;", "return 301 https://domain.com;", "set $a 1;", "}"}
+
+	_, location, err := parseLocationBlock(data, 0)
+
+	c.Assert(err, IsNil)
+	c.Assert(location.RewriteChain, HasLen, 3)
+	c.Assert(location.RewriteChain[0].Rewrite.Flag, Equals, REWRITE_LAST)
+	c.Assert(location.RewriteChain[1].Return, DeepEquals, &Return{Code: 301, Text: "https://domain.com"})
+	c.Assert(location.RewriteChain[2].Rewrite, IsNil)
+
+	// Invalid directives don't break parsing, errors are returned on evaluation
+	for _, line := range []string{"rewrite ^/(a /b;", "return 1000;"} {
+		_, location, err = parseLocationBlock([]string{line, "}"}, 0)
+		c.Assert(err, IsNil, Commentf("Directive: %s", line))
+
+		_, err = location.Rewrite("/", nil, nil)
+		c.Assert(err, NotNil, Commentf("Directive: %s", line))
+
+		_, server, err := parseServerBlock([]string{line, "}"}, 0)
+		c.Assert(err, IsNil, Commentf("Directive: %s", line))
+
+		_, err = server.Rewrite("/", nil, nil)
+		c.Assert(err, NotNil, Commentf("Directive: %s", line))
+	}
+}
+
 func (s *NginxSuite) TestLocationBlockParser(c *C) {
 	data := []string{"if ($k == 1) {", "return 100;"}
 
@@ -264,16 +298,16 @@ func (s *NginxSuite) TestPropsGetters(c *C) {
 	bfn4, bfs4, e4 := p.GetBuf("buffer4")
 
 	c.Assert(bfn1, Equals, int64(4))
-	c.Assert(bfs1, Equals, int64(16384))
+	c.Assert(bfs1, Equals, Size(16384))
 	c.Assert(e1, IsNil)
 	c.Assert(bfn2, Equals, int64(0))
-	c.Assert(bfs2, Equals, int64(0))
+	c.Assert(bfs2, Equals, Size(0))
 	c.Assert(e2, NotNil)
 	c.Assert(bfn3, Equals, int64(0))
-	c.Assert(bfs3, Equals, int64(0))
+	c.Assert(bfs3, Equals, Size(0))
 	c.Assert(e3, NotNil)
 	c.Assert(bfn4, Equals, int64(0))
-	c.Assert(bfs4, Equals, int64(0))
+	c.Assert(bfs4, Equals, Size(0))
 	c.Assert(e4, NotNil)
 
 	s1, e1 := p.GetSize("size1")
@@ -282,15 +316,15 @@ func (s *NginxSuite) TestPropsGetters(c *C) {
 	s4, e4 := p.GetSize("size4")
 	s5, e5 := p.GetSize("size5")
 
-	c.Assert(s1, Equals, int64(160))
+	c.Assert(s1, Equals, Size(160))
 	c.Assert(e1, IsNil)
-	c.Assert(s2, Equals, int64(524288))
+	c.Assert(s2, Equals, Size(524288))
 	c.Assert(e2, IsNil)
-	c.Assert(s3, Equals, int64(8388608))
+	c.Assert(s3, Equals, Size(8388608))
 	c.Assert(e3, IsNil)
-	c.Assert(s4, Equals, int64(2147483648))
+	c.Assert(s4, Equals, Size(2147483648))
 	c.Assert(e4, IsNil)
-	c.Assert(s5, Equals, int64(0))
+	c.Assert(s5, Equals, Size(0))
 	c.Assert(e5, NotNil)
 
 	t0, e0 := p.GetTime("time0")
@@ -393,16 +427,16 @@ func (s *NginxSuite) TestCondPropsGetters(c *C) {
 	bfn4, bfs4, e4 := p.GetBuf("buffer4")
 
 	c.Assert(bfn1, Equals, int64(4))
-	c.Assert(bfs1, Equals, int64(16384))
+	c.Assert(bfs1, Equals, Size(16384))
 	c.Assert(e1, IsNil)
 	c.Assert(bfn2, Equals, int64(0))
-	c.Assert(bfs2, Equals, int64(0))
+	c.Assert(bfs2, Equals, Size(0))
 	c.Assert(e2, NotNil)
 	c.Assert(bfn3, Equals, int64(0))
-	c.Assert(bfs3, Equals, int64(0))
+	c.Assert(bfs3, Equals, Size(0))
 	c.Assert(e3, NotNil)
 	c.Assert(bfn4, Equals, int64(0))
-	c.Assert(bfs4, Equals, int64(0))
+	c.Assert(bfs4, Equals, Size(0))
 	c.Assert(e4, NotNil)
 
 	s1, e1 := p.GetSize("size1")
@@ -411,15 +445,15 @@ func (s *NginxSuite) TestCondPropsGetters(c *C) {
 	s4, e4 := p.GetSize("size4")
 	s5, e5 := p.GetSize("size5")
 
-	c.Assert(s1, Equals, int64(160))
+	c.Assert(s1, Equals, Size(160))
 	c.Assert(e1, IsNil)
-	c.Assert(s2, Equals, int64(524288))
+	c.Assert(s2, Equals, Size(524288))
 	c.Assert(e2, IsNil)
-	c.Assert(s3, Equals, int64(8388608))
+	c.Assert(s3, Equals, Size(8388608))
 	c.Assert(e3, IsNil)
-	c.Assert(s4, Equals, int64(2147483648))
+	c.Assert(s4, Equals, Size(2147483648))
 	c.Assert(e4, IsNil)
-	c.Assert(s5, Equals, int64(0))
+	c.Assert(s5, Equals, Size(0))
 	c.Assert(e5, NotNil)
 
 	t0, e0 := p.GetTime("time0")
@@ -468,3 +502,37 @@ func (s *NginxSuite) TestCondPropsGetters(c *C) {
 	_, err = p.GetTime("unknown")
 	c.Assert(err, NotNil)
 }
+
+func (s *NginxSuite) TestMultiValueGetters(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	location := config.HTTP.FindServer("service.domain.com", "https").Locations[1]
+
+	c.Assert(location.Properties.GetAll("proxy_set_header"), DeepEquals, [][]string{
+		{"Host", "$host"},
+		{"X-Real-IP", "$remote_addr"},
+		{"X-Forwarded-For", "$proxy_add_x_forwarded_for"},
+	})
+	c.Assert(location.Properties.GetMap("proxy_set_header"), DeepEquals, map[string]string{
+		"Host":            "$host",
+		"X-Real-IP":       "$remote_addr",
+		"X-Forwarded-For": "$proxy_add_x_forwarded_for",
+	})
+	c.Assert(location.Properties.GetAll("return"), IsNil)
+	c.Assert(location.Properties.GetAll("return", 0), DeepEquals, [][]string{{"403"}})
+	c.Assert(location.Properties.GetMap("health_check"), DeepEquals, map[string]string{})
+	c.Assert(location.Properties.GetMap("unknown"), IsNil)
+
+	p := Properties{"add_header": {`X-A "a b" always`, "X-B b"}}
+
+	c.Assert(p.GetAll("add_header"), DeepEquals, [][]string{{"X-A", "a b", "always"}, {"X-B", "b"}})
+	c.Assert(p.GetMap("add_header"), DeepEquals, map[string]string{"X-A": "a b", "X-B": "b"})
+	c.Assert(p.GetAll("unknown"), IsNil)
+
+	var cp *ConditionalProperties
+
+	c.Assert(cp.GetAll("add_header"), IsNil)
+	c.Assert(argsToMap([][]string{{}}), DeepEquals, map[string]string{})
+}
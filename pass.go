@@ -0,0 +1,302 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Pass contains parsed target of proxy_pass, fastcgi_pass, grpc_pass, uwsgi_pass,
+// scgi_pass or memcached_pass directive
+type Pass struct {
+	Directive   string
+	Value       string
+	Scheme      string // Empty for directives without scheme (fastcgi_pass…)
+	Host        string
+	Port        int    // Zero if port is not set
+	URI         string // URI part (proxy_pass only)
+	Socket      string // Path to unix socket
+	Variables   bool   // Target contains variables
+	ConditionID int
+	Context     string
+	Location    *Location
+	Upstream    *Upstream
+
+	port string // Raw port value (may contain variables)
+}
+
+// UpstreamReport contains info about links between passes and upstreams
+type UpstreamReport struct {
+	Dangling  []*Pass  // Passes which refer to upstreams which don't exist
+	Conflicts []*Pass  // Passes with port which host matches upstream name
+	Unused    []string // Upstreams which aren't used in any pass
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// passSchemes contains supported schemes for pass directives
+var passSchemes = map[string][]string{
+	"proxy_pass":     {"http", "https"},
+	"grpc_pass":      {"grpc", "grpcs", ""},
+	"uwsgi_pass":     {"uwsgi", "suwsgi", ""},
+	"fastcgi_pass":   {""},
+	"scgi_pass":      {""},
+	"memcached_pass": {""},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParsePass parses value of pass directive
+func ParsePass(directive, value string) (*Pass, error) {
+	schemes, ok := passSchemes[directive]
+
+	if !ok {
+		return nil, fmt.Errorf("Unsupported pass directive %s", directive)
+	}
+
+	pass := &Pass{
+		Directive:   directive,
+		Value:       value,
+		ConditionID: -1,
+		Variables:   strings.Contains(value, "$"),
+	}
+
+	data := value
+
+	if index := strings.Index(data, "://"); index != -1 {
+		pass.Scheme = strings.ToLower(data[:index])
+		data = data[index+3:]
+	}
+
+	if !containsString(schemes, pass.Scheme) {
+		if pass.Scheme == "" {
+			return nil, fmt.Errorf("Invalid %s value %s: scheme is required", directive, value)
+		}
+
+		return nil, fmt.Errorf("Invalid %s value %s: unsupported scheme %s", directive, value, pass.Scheme)
+	}
+
+	if pass.Variables && (strings.HasPrefix(data, "$") || data == "") {
+		return pass, nil
+	}
+
+	if strings.HasPrefix(data, "unix:") {
+		return pass, pass.parseSocket(data[5:])
+	}
+
+	hostPort := data
+
+	if index := strings.IndexByte(data, '/'); index != -1 {
+		hostPort, pass.URI = data[:index], data[index:]
+	}
+
+	if pass.URI != "" && directive != "proxy_pass" {
+		return nil, fmt.Errorf("Invalid %s value %s: URI is not allowed", directive, value)
+	}
+
+	return pass, pass.parseHostPort(hostPort)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsUpstreamReference returns true if pass target looks like a reference to
+// upstream (host name without port and domain part)
+func (p *Pass) IsUpstreamReference() bool {
+	switch {
+	case p == nil:
+		return false
+	case p.Upstream != nil:
+		return true
+	case p.Variables, p.Socket != "", p.Port != 0, p.Host == "",
+		p.Host == "localhost", strings.Contains(p.Host, "."),
+		net.ParseIP(strings.Trim(p.Host, "[]")) != nil:
+		return false
+	}
+
+	return true
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetPasses returns all passes defined in locations with resolved upstreams
+func (h *HTTP) GetPasses() ([]*Pass, error) {
+	var result []*Pass
+
+	if h == nil {
+		return nil, nil
+	}
+
+	for serverIndex, server := range h.Servers {
+		for _, location := range server.Locations {
+			context := getLocationContext(getServerContext(serverIndex), location)
+
+			var directives []string
+
+			for directive := range location.Properties.Data {
+				directives = append(directives, directive)
+			}
+
+			for _, directive := range sortStrings(directives) {
+				if passSchemes[directive] == nil {
+					continue
+				}
+
+				for _, prop := range location.Properties.Data[directive] {
+					pass, err := ParsePass(directive, prop.Value)
+
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", context, err)
+					}
+
+					pass.ConditionID = prop.ConditionID
+					pass.Context = context
+					pass.Location = location
+					pass.Upstream = h.findPassUpstream(pass)
+
+					result = append(result, pass)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// CheckUpstreams returns report with dangling references to upstreams and
+// unused upstreams
+func (h *HTTP) CheckUpstreams() (*UpstreamReport, error) {
+	passes, err := h.GetPasses()
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UpstreamReport{}
+	used := make(map[string]bool)
+
+	for _, pass := range passes {
+		switch {
+		case pass.Upstream != nil:
+			used[pass.Upstream.Name] = true
+		case pass.port != "" && h.Upstreams[pass.getUpstreamName()] != nil:
+			report.Conflicts = append(report.Conflicts, pass)
+		case pass.IsUpstreamReference():
+			report.Dangling = append(report.Dangling, pass)
+		}
+	}
+
+	var names []string
+
+	for name := range h.Upstreams {
+		names = append(names, name)
+	}
+
+	for _, name := range sortStrings(names) {
+		if !used[name] {
+			report.Unused = append(report.Unused, name)
+		}
+	}
+
+	return report, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// findPassUpstream returns upstream used by pass (upstream is not used if
+// port is defined)
+func (h *HTTP) findPassUpstream(pass *Pass) *Upstream {
+	if pass.Socket != "" || pass.port != "" {
+		return nil
+	}
+
+	return h.Upstreams[pass.getUpstreamName()]
+}
+
+// getUpstreamName returns host name without variables which follow it
+// (e.g. backend for http://backend$request_uri)
+func (p *Pass) getUpstreamName() string {
+	if index := strings.IndexByte(p.Host, '$'); index != -1 {
+		return p.Host[:index]
+	}
+
+	return p.Host
+}
+
+// parseSocket parses unix socket path (and URI for proxy_pass)
+func (p *Pass) parseSocket(data string) error {
+	p.Socket = data
+
+	if p.Directive == "proxy_pass" {
+		if index := strings.IndexByte(data, ':'); index != -1 {
+			p.Socket, p.URI = data[:index], data[index+1:]
+		}
+	}
+
+	if p.Socket == "" {
+		return fmt.Errorf("Invalid %s value %s: socket path is empty", p.Directive, p.Value)
+	}
+
+	return nil
+}
+
+// parseHostPort parses host and port
+func (p *Pass) parseHostPort(data string) error {
+	p.Host = data
+
+	if strings.HasPrefix(data, "[") {
+		index := strings.IndexByte(data, ']')
+
+		if index == -1 {
+			return fmt.Errorf("Invalid %s value %s: invalid IPv6 address", p.Directive, p.Value)
+		}
+
+		p.Host, data = data[:index+1], data[index+1:]
+
+		if data == "" {
+			return nil
+		}
+
+		if data[0] != ':' {
+			return fmt.Errorf("Invalid %s value %s: invalid IPv6 address", p.Directive, p.Value)
+		}
+
+		data = data[1:]
+	} else if index := strings.LastIndexByte(data, ':'); index != -1 {
+		p.Host, data = data[:index], data[index+1:]
+	} else {
+		data = ""
+	}
+
+	if p.Host == "" {
+		return fmt.Errorf("Invalid %s value %s: host is empty", p.Directive, p.Value)
+	}
+
+	if data == "" {
+		return nil
+	}
+
+	p.port = data
+	port, err := strconv.Atoi(data)
+
+	if err != nil || port < 1 || port > 65535 {
+		if p.Variables && strings.Contains(data, "$") {
+			return nil
+		}
+
+		return fmt.Errorf("Invalid %s value %s: invalid port %s", p.Directive, p.Value, data)
+	}
+
+	p.Port = port
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestPassParsing(c *C) {
+	pass, err := ParsePass("proxy_pass", "http://123.0.0.111:80/")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Scheme, Equals, "http")
+	c.Assert(pass.Host, Equals, "123.0.0.111")
+	c.Assert(pass.Port, Equals, 80)
+	c.Assert(pass.URI, Equals, "/")
+	c.Assert(pass.IsUpstreamReference(), Equals, false)
+
+	pass, err = ParsePass("proxy_pass", "https://backend")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Host, Equals, "backend")
+	c.Assert(pass.Port, Equals, 0)
+	c.Assert(pass.URI, Equals, "")
+	c.Assert(pass.IsUpstreamReference(), Equals, true)
+
+	pass, err = ParsePass("proxy_pass", "http://[::1]:8080/api/")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Host, Equals, "[::1]")
+	c.Assert(pass.Port, Equals, 8080)
+	c.Assert(pass.URI, Equals, "/api/")
+
+	pass, err = ParsePass("proxy_pass", "http://unix:/tmp/backend.socket:/uri/")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Socket, Equals, "/tmp/backend.socket")
+	c.Assert(pass.URI, Equals, "/uri/")
+
+	pass, err = ParsePass("fastcgi_pass", "unix:/run/php-fpm.sock")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Scheme, Equals, "")
+	c.Assert(pass.Socket, Equals, "/run/php-fpm.sock")
+
+	pass, err = ParsePass("grpc_pass", "grpcs://grpc-backend")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Scheme, Equals, "grpcs")
+
+	pass, err = ParsePass("uwsgi_pass", "127.0.0.1:9000")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Host, Equals, "127.0.0.1")
+	c.Assert(pass.Port, Equals, 9000)
+
+	pass, err = ParsePass("proxy_pass", "http://$backend$request_uri")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Variables, Equals, true)
+	c.Assert(pass.Host, Equals, "")
+	c.Assert(pass.IsUpstreamReference(), Equals, false)
+
+	pass, err = ParsePass("proxy_pass", "http://backend:$port")
+
+	c.Assert(err, IsNil)
+	c.Assert(pass.Host, Equals, "backend")
+	c.Assert(pass.Port, Equals, 0)
+
+	_, err = ParsePass("root", "/srv")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("proxy_pass", "backend")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("proxy_pass", "ftp://backend")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("fastcgi_pass", "127.0.0.1:9000/uri")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("proxy_pass", "http://backend:99999")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("proxy_pass", "http://:80")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("proxy_pass", "http://[::1")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("proxy_pass", "http://[::1]80")
+	c.Assert(err, NotNil)
+	_, err = ParsePass("fastcgi_pass", "unix:")
+	c.Assert(err, NotNil)
+
+	c.Assert((*Pass)(nil).IsUpstreamReference(), Equals, false)
+}
+
+func (s *NginxSuite) TestUpstreamLinks(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+	c.Assert(config.HTTP.Upstreams["dav-staging"].Name, Equals, "dav-staging")
+
+	passes, err := config.HTTP.GetPasses()
+
+	c.Assert(err, IsNil)
+	c.Assert(passes, HasLen, 1)
+	c.Assert(passes[0].Context, Equals, "http/server[2]/location[/]")
+	c.Assert(passes[0].Upstream, IsNil)
+
+	report, err := config.HTTP.CheckUpstreams()
+
+	c.Assert(err, IsNil)
+	c.Assert(report.Dangling, HasLen, 0)
+	c.Assert(report.Unused, DeepEquals, []string{"dav-staging"})
+
+	location := passes[0].Location
+	location.Properties.Data["proxy_pass"] = []ConditionalProperty{
+		{-1, "http://dav-staging/"}, {-1, "http://api-backend"},
+	}
+
+	passes, err = config.HTTP.GetPasses()
+
+	c.Assert(err, IsNil)
+	c.Assert(passes[0].Upstream, Equals, config.HTTP.Upstreams["dav-staging"])
+
+	report, err = config.HTTP.CheckUpstreams()
+
+	c.Assert(err, IsNil)
+	c.Assert(report.Dangling, HasLen, 1)
+	c.Assert(report.Dangling[0].Host, Equals, "api-backend")
+	c.Assert(report.Conflicts, IsNil)
+	c.Assert(report.Unused, IsNil)
+
+	location.Properties.Data["proxy_pass"] = []ConditionalProperty{
+		{-1, "http://dav-staging$request_uri"}, {-1, "http://dav-staging:8080"}, {-1, "http://dav-staging:$port"},
+	}
+
+	passes, err = config.HTTP.GetPasses()
+
+	c.Assert(err, IsNil)
+	c.Assert(passes[0].Upstream, Equals, config.HTTP.Upstreams["dav-staging"])
+	c.Assert(passes[1].Upstream, IsNil)
+	c.Assert(passes[2].Upstream, IsNil)
+
+	report, err = config.HTTP.CheckUpstreams()
+
+	c.Assert(err, IsNil)
+	c.Assert(report.Dangling, IsNil)
+	c.Assert(report.Conflicts, DeepEquals, []*Pass{passes[1], passes[2]})
+	c.Assert(report.Unused, IsNil)
+
+	location.Properties.Data["proxy_pass"] = []ConditionalProperty{
+		{-1, "http://dav-staging/"}, {-1, "http://api-backend"},
+	}
+
+	trace, err := config.HTTP.Simulate(&Request{Scheme: "https", Host: "service.domain.com", URI: "/"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Pass.Host, Equals, "api-backend")
+
+	location.Properties.Data["proxy_pass"] = []ConditionalProperty{{-1, "backend"}}
+
+	_, err = config.HTTP.GetPasses()
+	c.Assert(err, NotNil)
+	_, err = config.HTTP.CheckUpstreams()
+	c.Assert(err, NotNil)
+	_, err = config.HTTP.Simulate(&Request{Scheme: "https", Host: "service.domain.com", URI: "/"}, nil)
+	c.Assert(err, NotNil)
+
+	passes, err = (*HTTP)(nil).GetPasses()
+
+	c.Assert(err, IsNil)
+	c.Assert(passes, IsNil)
+}
@@ -0,0 +1,428 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	REWRITE_NONE      = ""
+	REWRITE_LAST      = "last"
+	REWRITE_BREAK     = "break"
+	REWRITE_REDIRECT  = "redirect"
+	REWRITE_PERMANENT = "permanent"
+)
+
+// MAX_REWRITE_CYCLES is maximum number of URI changes (same as in NGINX)
+const MAX_REWRITE_CYCLES = 10
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Return contains info about return directive
+type Return struct {
+	Code int
+	Text string // Response body or redirect URL
+}
+
+// Rewrite contains info about rewrite directive
+type Rewrite struct {
+	Regex       string
+	Replacement string
+	Flag        string
+
+	regex *regexp.Regexp
+}
+
+// RewriteResult contains result of rewrite chain processing
+type RewriteResult struct {
+	URI        string
+	Args       string
+	Applied    []string // Applied rewrites ("old → new")
+	Conditions []*ConditionResult
+	Return     *Return // Return or redirect (if any)
+	Flag       string  // Flag of rewrite (or break) which stopped processing
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseRewrite parses rewrite directive value
+func ParseRewrite(data string) (*Rewrite, error) {
+	args := parseArgs(data)
+
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("Invalid number of rewrite parameters")
+	}
+
+	rewrite := &Rewrite{Regex: args[0], Replacement: args[1]}
+
+	if len(args) == 3 {
+		switch args[2] {
+		case REWRITE_LAST, REWRITE_BREAK, REWRITE_REDIRECT, REWRITE_PERMANENT:
+			rewrite.Flag = args[2]
+		default:
+			return nil, fmt.Errorf("Unsupported rewrite flag %s", args[2])
+		}
+	}
+
+	var err error
+
+	rewrite.regex, err = compileRegexp(rewrite.Regex, false)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rewrite regex %s: %v", rewrite.Regex, err)
+	}
+
+	return rewrite, nil
+}
+
+// ParseReturn parses return directive value
+func ParseReturn(data string) (*Return, error) {
+	args := parseArgs(data)
+
+	switch len(args) {
+	case 1:
+		code, err := parseStatusCode(args[0])
+
+		if err == nil {
+			return &Return{Code: code}, nil
+		}
+
+		if !isRedirectURL(args[0]) {
+			return nil, fmt.Errorf("Invalid return value %s: status code or URL expected", args[0])
+		}
+
+		return &Return{Code: 302, Text: args[0]}, nil
+
+	case 2:
+		code, err := parseStatusCode(args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Return{Code: code, Text: args[1]}, nil
+	}
+
+	return nil, fmt.Errorf("Invalid number of return parameters")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsRedirect returns true if return is a redirect
+func (r *Return) IsRedirect() bool {
+	if r == nil {
+		return false
+	}
+
+	switch r.Code {
+	case 301, 302, 303, 307, 308:
+		return true
+	}
+
+	return false
+}
+
+// IsRedirect returns true if rewrite returns redirect instead of URI change
+func (r *Rewrite) IsRedirect() bool {
+	if r == nil {
+		return false
+	}
+
+	return r.Flag == REWRITE_REDIRECT || r.Flag == REWRITE_PERMANENT ||
+		isRedirectURL(r.Replacement)
+}
+
+// Apply applies rewrite to given URI and arguments and returns new URI and
+// arguments (if regex doesn't match URI, ok will be false)
+func (r *Rewrite) Apply(uri, args string, vars map[string]string) (string, string, bool) {
+	groups := r.regex.FindStringSubmatch(uri)
+
+	if groups == nil {
+		return uri, args, false
+	}
+
+	replacement := r.Replacement
+	keepArgs := !strings.HasSuffix(replacement, "?")
+
+	if !keepArgs {
+		replacement = strings.TrimSuffix(replacement, "?")
+	}
+
+	result := expandRewriteValue(replacement, r.regex, groups, vars)
+	newURI, newArgs := result, ""
+
+	if index := strings.IndexByte(result, '?'); index != -1 {
+		newURI, newArgs = result[:index], result[index+1:]
+	}
+
+	if keepArgs && args != "" {
+		if newArgs != "" {
+			newArgs += "&" + args
+		} else {
+			newArgs = args
+		}
+	}
+
+	return newURI, newArgs, true
+}
+
+// String returns rewrite as a string
+func (r *Rewrite) String() string {
+	if r == nil {
+		return ""
+	}
+
+	if r.Flag == REWRITE_NONE {
+		return r.Regex + " " + r.Replacement
+	}
+
+	return r.Regex + " " + r.Replacement + " " + r.Flag
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Rewrite applies server rewrite chain to given URI (with query string)
+func (s *Server) Rewrite(uri string, vars map[string]string, fs FileSystem) (*RewriteResult, error) {
+	if s == nil {
+		return nil, fmt.Errorf("Server is nil")
+	}
+
+	return applyRewriteChain(s.RewriteChain, s.Properties, uri, vars, fs)
+}
+
+// Rewrite applies location rewrite chain to given URI (with query string)
+func (l *Location) Rewrite(uri string, vars map[string]string, fs FileSystem) (*RewriteResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("Location is nil")
+	}
+
+	return applyRewriteChain(l.RewriteChain, l.Properties, uri, vars, fs)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// applyRewriteChain applies rewrite module directives to given URI, variables
+// map is updated with values from set directives and regex captures
+func applyRewriteChain(chain []RewriteDirective, props *ConditionalProperties, uri string, vars map[string]string, fs FileSystem) (*RewriteResult, error) {
+	result := &RewriteResult{URI: uri}
+
+	if index := strings.IndexByte(uri, '?'); index != -1 {
+		result.URI, result.Args = uri[:index], uri[index+1:]
+	}
+
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+
+	active := map[int]bool{-1: true}
+
+	for _, directive := range chain {
+		vars["uri"], vars["args"] = result.URI, result.Args
+
+		if directive.Name == "if" {
+			cond, err := props.GetCondition(directive.ConditionID)
+
+			if err != nil {
+				return nil, err
+			}
+
+			ok, groups, err := cond.evaluate(vars, fs)
+
+			if err != nil {
+				return nil, err
+			}
+
+			setCaptureVariables(vars, cond.regex, groups)
+			active[directive.ConditionID] = ok
+			result.Conditions = append(result.Conditions, &ConditionResult{
+				Condition: cond.String(), Result: ok,
+			})
+
+			continue
+		}
+
+		if !active[directive.ConditionID] {
+			continue
+		}
+
+		switch directive.Name {
+		case "break":
+			result.Flag = REWRITE_BREAK
+			return result, nil
+
+		case "set":
+			args := parseArgs(directive.Value)
+
+			if len(args) != 2 || !strings.HasPrefix(args[0], "$") {
+				return nil, fmt.Errorf("Invalid set directive %q", directive.Value)
+			}
+
+			vars[strings.TrimPrefix(args[0], "$")] = expandVariables(args[1], vars)
+
+		case "return":
+			ret, err := directive.getReturn()
+
+			if err != nil {
+				return nil, err
+			}
+
+			result.Return = &Return{Code: ret.Code, Text: expandVariables(ret.Text, vars)}
+
+			return result, nil
+
+		case "rewrite":
+			rewrite, err := directive.getRewrite()
+
+			if err != nil {
+				return nil, err
+			}
+
+			newURI, newArgs, ok := rewrite.Apply(result.URI, result.Args, vars)
+
+			if !ok {
+				continue
+			}
+
+			setCaptureVariables(vars, rewrite.regex, rewrite.regex.FindStringSubmatch(result.URI))
+
+			if rewrite.IsRedirect() {
+				result.Return = getRewriteRedirect(rewrite, newURI, newArgs)
+				result.Applied = append(result.Applied, result.URI+" → "+result.Return.Text)
+				result.Flag = rewrite.Flag
+
+				return result, nil
+			}
+
+			result.Applied = append(result.Applied, result.URI+" → "+newURI)
+			result.URI, result.Args = newURI, newArgs
+
+			if rewrite.Flag != REWRITE_NONE {
+				result.Flag = rewrite.Flag
+				vars["uri"], vars["args"] = result.URI, result.Args
+				return result, nil
+			}
+		}
+	}
+
+	vars["uri"], vars["args"] = result.URI, result.Args
+
+	return result, nil
+}
+
+// getRewrite returns parsed rewrite (directives added to chain manually
+// are parsed on demand)
+func (d RewriteDirective) getRewrite() (*Rewrite, error) {
+	switch {
+	case d.err != nil:
+		return nil, fmt.Errorf("Invalid rewrite directive %q: %v", d.Value, d.err)
+	case d.Rewrite != nil:
+		return d.Rewrite, nil
+	}
+
+	return ParseRewrite(d.Value)
+}
+
+// getReturn returns parsed return (directives added to chain manually
+// are parsed on demand)
+func (d RewriteDirective) getReturn() (*Return, error) {
+	switch {
+	case d.err != nil:
+		return nil, fmt.Errorf("Invalid return directive %q: %v", d.Value, d.err)
+	case d.Return != nil:
+		return d.Return, nil
+	}
+
+	return ParseReturn(d.Value)
+}
+
+// getRewriteRedirect creates redirect for rewrite
+func getRewriteRedirect(rewrite *Rewrite, uri, args string) *Return {
+	ret := &Return{Code: 302, Text: uri}
+
+	if rewrite.Flag == REWRITE_PERMANENT {
+		ret.Code = 301
+	}
+
+	if args != "" {
+		ret.Text += "?" + args
+	}
+
+	return ret
+}
+
+// expandRewriteValue expands regex captures and variables in rewrite replacement
+func expandRewriteValue(data string, re *regexp.Regexp, groups []string, vars map[string]string) string {
+	names := re.SubexpNames()
+
+	return replaceVariables(data, func(name, ref string) string {
+		if isNumericVariable(name) {
+			index := int(name[0] - '0')
+
+			if index < len(groups) {
+				return groups[index]
+			}
+
+			return ""
+		}
+
+		for i, n := range names {
+			if n != "" && n == name {
+				return groups[i]
+			}
+		}
+
+		return vars[name]
+	})
+}
+
+// setCaptureVariables sets numeric ($1-$9) and named capture variables from
+// regex groups
+func setCaptureVariables(vars map[string]string, re *regexp.Regexp, groups []string) {
+	if re == nil || groups == nil {
+		return
+	}
+
+	for i, name := range re.SubexpNames() {
+		switch {
+		case i == 0:
+			continue
+		case name != "":
+			vars[name] = groups[i]
+		}
+
+		if i < 10 {
+			vars[strconv.Itoa(i)] = groups[i]
+		}
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isRedirectURL returns true if given value is URL which can be used for redirect
+func isRedirectURL(data string) bool {
+	return strings.HasPrefix(data, "http://") ||
+		strings.HasPrefix(data, "https://") ||
+		strings.HasPrefix(data, "$scheme")
+}
+
+// parseStatusCode parses HTTP status code
+func parseStatusCode(data string) (int, error) {
+	code, err := strconv.Atoi(data)
+
+	if err != nil || code < 0 || code > 999 {
+		return 0, fmt.Errorf("Invalid status code %s", data)
+	}
+
+	return code, nil
+}
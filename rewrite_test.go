@@ -0,0 +1,230 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const rewriteTestConfig = `
+server {
+  listen 80;
+  server_name rewrite.domain.com;
+
+  rewrite ^/old/(.*)$ /new/$1;
+  set $backend "main";
+
+  if ($arg_legacy = "1") {
+    set $backend "legacy";
+    rewrite ^/new/(?<page>.+)$ /legacy/$page? break;
+  }
+
+  location /new/ {
+    rewrite ^/new/(\d+)$ /items/$1 last;
+    rewrite ^/new/loop /new/loop last;
+    rewrite ^/new/(.*)$ https://$host/v2/$1 permanent;
+  }
+
+  location /items/ {
+    return 200 "item $uri";
+  }
+
+  location /legacy/ {
+    alias /srv/legacy/;
+  }
+}
+`
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestRewriteParsing(c *C) {
+	rewrite, err := ParseRewrite(`^/(.*)$ /index.php?q=$1 last`)
+
+	c.Assert(err, IsNil)
+	c.Assert(rewrite.Regex, Equals, "^/(.*)$")
+	c.Assert(rewrite.Replacement, Equals, "/index.php?q=$1")
+	c.Assert(rewrite.Flag, Equals, REWRITE_LAST)
+	c.Assert(rewrite.IsRedirect(), Equals, false)
+	c.Assert(rewrite.String(), Equals, "^/(.*)$ /index.php?q=$1 last")
+
+	uri, args, ok := rewrite.Apply("/test", "a=1", nil)
+
+	c.Assert(ok, Equals, true)
+	c.Assert(uri, Equals, "/index.php")
+	c.Assert(args, Equals, "q=test&a=1")
+
+	rewrite, err = ParseRewrite(`^ https://service.domain.com$request_uri? permanent`)
+
+	c.Assert(err, IsNil)
+	c.Assert(rewrite.IsRedirect(), Equals, true)
+
+	uri, args, ok = rewrite.Apply("/a", "b=1", map[string]string{"request_uri": "/a?b=1"})
+
+	c.Assert(ok, Equals, true)
+	c.Assert(uri, Equals, "https://service.domain.com/a")
+	c.Assert(args, Equals, "b=1")
+
+	rewrite, err = ParseRewrite(`^/(?<name>[a-z]+)$ /$name/$2`)
+
+	c.Assert(err, IsNil)
+	c.Assert(rewrite.String(), Equals, "^/(?<name>[a-z]+)$ /$name/$2")
+
+	uri, _, _ = rewrite.Apply("/abc", "", nil)
+	c.Assert(uri, Equals, "/abc/")
+
+	_, _, ok = rewrite.Apply("/123", "", nil)
+	c.Assert(ok, Equals, false)
+
+	ret, err := ParseReturn("https://domain.com")
+
+	c.Assert(err, IsNil)
+	c.Assert(ret, DeepEquals, &Return{Code: 302, Text: "https://domain.com"})
+	c.Assert(ret.IsRedirect(), Equals, true)
+
+	ret, err = ParseReturn(`404 "Not found"`)
+
+	c.Assert(err, IsNil)
+	c.Assert(ret, DeepEquals, &Return{Code: 404, Text: "Not found"})
+	c.Assert(ret.IsRedirect(), Equals, false)
+
+	_, err = ParseRewrite("^/")
+	c.Assert(err, NotNil)
+	_, err = ParseRewrite("^/ /a forever")
+	c.Assert(err, NotNil)
+	_, err = ParseRewrite("^/(a /a")
+	c.Assert(err, NotNil)
+	ret, err = ParseReturn("$scheme://domain.com$request_uri")
+
+	c.Assert(err, IsNil)
+	c.Assert(ret.Code, Equals, 302)
+
+	for _, data := range []string{"abc def", "", "foo", "1000", "/index.html", "-1"} {
+		_, err = ParseReturn(data)
+		c.Assert(err, NotNil, Commentf("Return: %s", data))
+	}
+
+	c.Assert((*Rewrite)(nil).IsRedirect(), Equals, false)
+	c.Assert((*Rewrite)(nil).String(), Equals, "")
+	c.Assert((*Return)(nil).IsRedirect(), Equals, false)
+}
+
+func (s *NginxSuite) TestRewriteChain(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	server := config.HTTP.FindServer("service.domain.com", "http")
+	vars := map[string]string{"request_uri": "/a?b=1"}
+	result, err := server.Rewrite("/a?b=1", vars, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(result.Return, DeepEquals, &Return{Code: 301, Text: "https://service.domain.com/a?b=1"})
+	c.Assert(result.Flag, Equals, REWRITE_PERMANENT)
+
+	trace, err := config.HTTP.Simulate(&Request{Host: "service.domain.com", URI: "/a?b=1"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Location, IsNil)
+	c.Assert(trace.Rewrites, DeepEquals, []string{"/a → https://service.domain.com/a?b=1"})
+	c.Assert(trace.Return.Code, Equals, 301)
+
+	file := c.MkDir() + "/rewrite.conf"
+	c.Assert(ioutil.WriteFile(file, []byte(rewriteTestConfig), 0644), IsNil)
+
+	http, err := ReadPart(file, "")
+
+	c.Assert(err, IsNil)
+
+	server = http.Servers[0]
+
+	c.Assert(server.RewriteChain, HasLen, 5)
+	c.Assert(server.RewriteChain[2], DeepEquals, RewriteDirective{ConditionID: 0, Name: "if", Value: `$arg_legacy = "1"`})
+
+	vars = map[string]string{"arg_legacy": "1"}
+	result, err = server.Rewrite("/old/page?legacy=1", vars, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(result.URI, Equals, "/legacy/page")
+	c.Assert(result.Args, Equals, "")
+	c.Assert(result.Flag, Equals, REWRITE_BREAK)
+	c.Assert(result.Applied, DeepEquals, []string{"/old/page → /new/page", "/new/page → /legacy/page"})
+	c.Assert(result.Conditions[0].Result, Equals, true)
+	c.Assert(vars["backend"], Equals, "legacy")
+	c.Assert(vars["page"], Equals, "page")
+	c.Assert(vars["1"], Equals, "page")
+
+	trace, err = http.Simulate(&Request{Host: "rewrite.domain.com", URI: "/old/page?legacy=1"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Location.URI, Equals, "/legacy/")
+	c.Assert(trace.Target, Equals, "/srv/legacy/page")
+
+	trace, err = http.Simulate(&Request{Host: "rewrite.domain.com", URI: "/old/15"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Location.URI, Equals, "/items/")
+	c.Assert(trace.Variables["backend"], Equals, "main")
+	c.Assert(trace.Return, DeepEquals, &Return{Code: 200, Text: "item /items/15"})
+	c.Assert(trace.Rewrites, DeepEquals, []string{"/old/15 → /new/15", "/new/15 → /items/15"})
+
+	trace, err = http.Simulate(&Request{Host: "rewrite.domain.com", URI: "/new/page"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Return, DeepEquals, &Return{Code: 301, Text: "https://rewrite.domain.com/v2/page"})
+
+	_, err = http.Simulate(&Request{Host: "rewrite.domain.com", URI: "/new/loop"}, nil)
+	c.Assert(err, NotNil)
+
+	_, err = (*Server)(nil).Rewrite("/", nil, nil)
+	c.Assert(err, NotNil)
+	_, err = (*Location)(nil).Rewrite("/", nil, nil)
+	c.Assert(err, NotNil)
+
+	chain := []RewriteDirective{{ConditionID: -1, Name: "set", Value: "$a"}}
+	_, err = applyRewriteChain(chain, nil, "/", nil, nil)
+	c.Assert(err, NotNil)
+
+	chain = []RewriteDirective{{ConditionID: -1, Name: "rewrite", Value: "^/(a /b"}}
+	_, err = applyRewriteChain(chain, nil, "/", nil, nil)
+	c.Assert(err, NotNil)
+
+	chain = []RewriteDirective{{ConditionID: -1, Name: "return", Value: "a b"}}
+	_, err = applyRewriteChain(chain, nil, "/", nil, nil)
+	c.Assert(err, NotNil)
+
+	chain = []RewriteDirective{{ConditionID: 0, Name: "if", Value: "$a"}}
+	_, err = applyRewriteChain(chain, &ConditionalProperties{}, "/", nil, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *NginxSuite) TestRewritePCRESyntax(c *C) {
+	file := c.MkDir() + "/pcre.conf"
+	data := "server {\n  listen 80;\n  server_name pcre.domain.com;\n\n" +
+		"  location / {\n    rewrite ^/(?!api/)(.*)$ /app/$1 last;\n    rewrite ^/(\\w++)$ /$1;\n  }\n}\n"
+
+	c.Assert(ioutil.WriteFile(file, []byte(data), 0644), IsNil)
+
+	http, err := ReadPart(file, "")
+
+	c.Assert(err, IsNil)
+
+	location := http.Servers[0].Locations[0]
+
+	c.Assert(location.RewriteChain, HasLen, 2)
+	c.Assert(location.RewriteChain[0].Rewrite, IsNil)
+
+	_, err = location.Rewrite("/index.html", nil, nil)
+	c.Assert(err, NotNil)
+
+	_, err = http.Simulate(&Request{Host: "pcre.domain.com", URI: "/index.html"}, nil)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,514 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Request contains info about synthetic request
+type Request struct {
+	Method     string
+	Scheme     string // "http" or "https"
+	Host       string
+	URI        string // Path with query string
+	Headers    map[string]string
+	RemoteAddr net.IP
+}
+
+// Trace contains info about request handling
+type Trace struct {
+	Server          *Server
+	Location        *Location
+	Conditions      []*ConditionResult
+	Rewrites        []string // Applied rewrites ("old → new")
+	Return          *Return
+	URI             string // URI after rewrites
+	Target          string // Final proxy_pass (or other *_pass) value or path to file
+	TargetDirective string // proxy_pass, fastcgi_pass, grpc_pass, uwsgi_pass, root, alias…
+	Pass            *Pass  // Parsed pass target (if request is passed to other server)
+	Headers         map[string][]string
+	ProxyHeaders    map[string]string
+	Variables       map[string]string
+
+	flag string
+}
+
+// ConditionResult contains info about evaluated condition
+type ConditionResult struct {
+	Context   string
+	Condition string
+	Result    bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// passDirectives is slice with directives which pass request to other server
+var passDirectives = []string{
+	"proxy_pass", "fastcgi_pass", "grpc_pass", "uwsgi_pass",
+	"scgi_pass", "memcached_pass",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Simulate computes request handling path for given synthetic request (if file
+// system is nil, OS file system will be used for file checks)
+func (h *HTTP) Simulate(req *Request, fs FileSystem) (*Trace, error) {
+	if h.ServersNum() == 0 {
+		return nil, fmt.Errorf("There are no servers in configuration")
+	}
+
+	if req == nil || req.URI == "" {
+		return nil, fmt.Errorf("Request URI is empty")
+	}
+
+	trace := &Trace{Headers: make(map[string][]string), ProxyHeaders: make(map[string]string)}
+	scheme := req.Scheme
+
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	trace.Server = h.MatchServer(req.Host, scheme)
+
+	if trace.Server == nil {
+		return nil, fmt.Errorf("Can't find server for host %s (%s)", req.Host, scheme)
+	}
+
+	trace.Variables = getRequestVariables(req, trace.Server)
+	trace.URI = trace.Variables["uri"]
+
+	err := trace.runRewritePhase(trace.Server.RewriteChain, trace.Server.Properties, "server", fs)
+
+	if err != nil || trace.Return != nil {
+		return trace, err
+	}
+
+	for cycle := 0; ; cycle++ {
+		if cycle == MAX_REWRITE_CYCLES {
+			return trace, fmt.Errorf("Rewrite or internal redirection cycle while processing %s", trace.URI)
+		}
+
+		var groups []string
+		var regex *regexp.Regexp
+
+		trace.Location, regex, groups, err = trace.Server.matchLocation(trace.URI)
+
+		if err != nil || trace.Location == nil {
+			return trace, err
+		}
+
+		setCaptureVariables(trace.Variables, regex, groups)
+
+		rewrites := len(trace.Rewrites)
+		err = trace.runRewritePhase(trace.Location.RewriteChain, trace.Location.Properties, "location", fs)
+
+		if err != nil || trace.Return != nil {
+			return trace, err
+		}
+
+		if trace.flag == REWRITE_BREAK || len(trace.Rewrites) == rewrites && trace.flag != REWRITE_LAST {
+			break
+		}
+	}
+
+	err = trace.resolveTarget(fs)
+
+	if err != nil {
+		return trace, err
+	}
+
+	err = trace.resolveHeaders(fs)
+
+	return trace, err
+}
+
+// MatchServer returns server which will handle request for given host and
+// protocol (http or https)
+func (h *HTTP) MatchServer(host, protocol string) *Server {
+	if h.ServersNum() == 0 {
+		return nil
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(stripPort(host), "."))
+
+	if server := h.FindServer(host, protocol); server != nil {
+		return server
+	}
+
+	var candidates []*Server
+
+	for _, server := range h.Servers {
+		protocols := server.GetProtocols()
+
+		if len(protocols) == 0 && protocol == "http" || isProtocolSupported(protocols, protocol) {
+			candidates = append(candidates, server)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if server := matchWildcardServer(candidates, host); server != nil {
+		return server
+	}
+
+	for _, server := range candidates {
+		for _, name := range server.GetNames() {
+			if !strings.HasPrefix(name, "~") {
+				continue
+			}
+
+			re, err := compileRegexp(name[1:], true)
+
+			if err == nil && re.MatchString(host) {
+				return server
+			}
+		}
+	}
+
+	for _, server := range candidates {
+		if containsString(server.GetProtocols(), "default_server") ||
+			containsString(server.GetProtocols(), "default") {
+			return server
+		}
+	}
+
+	return candidates[0]
+}
+
+// MatchLocation returns location which will handle request with given URI
+func (s *Server) MatchLocation(uri string) (*Location, error) {
+	location, _, _, err := s.matchLocation(uri)
+	return location, err
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// matchLocation finds location for given URI and returns location, regex used
+// for matching (if location is regex location) and regex groups
+func (s *Server) matchLocation(uri string) (*Location, *regexp.Regexp, []string, error) {
+	if s == nil {
+		return nil, nil, nil, nil
+	}
+
+	var prefix *Location
+
+	for _, location := range s.Locations {
+		switch location.Modifier {
+		case "=":
+			if location.URI == uri {
+				return location, nil, nil, nil
+			}
+
+		case "", "^~":
+			if strings.HasPrefix(location.URI, "@") || !strings.HasPrefix(uri, location.URI) {
+				continue
+			}
+
+			if prefix == nil || len(location.URI) > len(prefix.URI) {
+				prefix = location
+			}
+		}
+	}
+
+	if prefix != nil && prefix.Modifier == "^~" {
+		return prefix, nil, nil, nil
+	}
+
+	for _, location := range s.Locations {
+		if location.Modifier != "~" && location.Modifier != "~*" {
+			continue
+		}
+
+		re, err := compileRegexp(location.URI, location.Modifier == "~*")
+
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Can't compile regex %q of location: %v", location.URI, err)
+		}
+
+		groups := re.FindStringSubmatch(uri)
+
+		if groups != nil {
+			return location, re, groups, nil
+		}
+	}
+
+	return prefix, nil, nil, nil
+}
+
+// runRewritePhase applies rewrite chain and saves results to trace
+func (t *Trace) runRewritePhase(chain []RewriteDirective, props *ConditionalProperties, context string, fs FileSystem) error {
+	uri := t.URI
+
+	if t.Variables["args"] != "" {
+		uri += "?" + t.Variables["args"]
+	}
+
+	result, err := applyRewriteChain(chain, props, uri, t.Variables, fs)
+
+	if err != nil {
+		return err
+	}
+
+	for _, cond := range result.Conditions {
+		cond.Context = context
+	}
+
+	t.Conditions = append(t.Conditions, result.Conditions...)
+	t.Rewrites = append(t.Rewrites, result.Applied...)
+	t.Return = result.Return
+	t.URI = result.URI
+	t.flag = result.Flag
+
+	return nil
+}
+
+// resolveTarget resolves final target of request
+func (t *Trace) resolveTarget(fs FileSystem) error {
+	props := t.Location.Properties
+
+	for _, directive := range passDirectives {
+		values, err := props.GetApplicable(directive, t.Variables, fs)
+
+		if err != nil {
+			return err
+		}
+
+		if len(values) != 0 {
+			t.TargetDirective = directive
+			t.Target = expandVariables(values[len(values)-1], t.Variables)
+			t.Pass, err = ParsePass(directive, t.Target)
+
+			if err != nil {
+				return err
+			}
+
+			if t.Server.Parent != nil {
+				t.Pass.Location = t.Location
+				t.Pass.Upstream = t.Server.Parent.findPassUpstream(t.Pass)
+			}
+
+			return nil
+		}
+	}
+
+	alias := props.Get("alias")
+
+	if alias != "" {
+		alias = expandVariables(alias, t.Variables)
+		t.TargetDirective = "alias"
+
+		if t.Location.Modifier == "~" || t.Location.Modifier == "~*" {
+			t.Target = alias
+		} else {
+			t.Target = alias + strings.TrimPrefix(t.URI, t.Location.URI)
+		}
+
+		return nil
+	}
+
+	root := props.Get("root")
+
+	if root == "" {
+		root = t.Server.Properties.Get("root")
+	}
+
+	if root == "" && t.Server.Parent != nil {
+		root = t.Server.Parent.Properties.Get("root")
+	}
+
+	if root == "" {
+		root = "html"
+	}
+
+	t.TargetDirective = "root"
+	t.Target = expandVariables(root, t.Variables) + t.URI
+
+	return nil
+}
+
+// resolveHeaders resolves effective response and proxy headers
+func (t *Trace) resolveHeaders(fs FileSystem) error {
+	for _, directive := range []string{"add_header", "proxy_set_header"} {
+		values, err := t.getInheritedValues(directive, fs)
+
+		if err != nil {
+			return err
+		}
+
+		for _, value := range values {
+			args := parseArgs(value)
+
+			if len(args) < 2 {
+				continue
+			}
+
+			headerValue := expandVariables(args[1], t.Variables)
+
+			if directive == "add_header" {
+				t.Headers[args[0]] = append(t.Headers[args[0]], headerValue)
+			} else {
+				t.ProxyHeaders[args[0]] = headerValue
+			}
+		}
+	}
+
+	return nil
+}
+
+// getInheritedValues returns values of directive with respect to NGINX
+// inheritance rules (values are inherited only if there are no directives
+// with the same name on the current level)
+func (t *Trace) getInheritedValues(directive string, fs FileSystem) ([]string, error) {
+	for _, props := range []*ConditionalProperties{t.Location.Properties, t.Server.Properties} {
+		values, err := props.GetApplicable(directive, t.Variables, fs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) != 0 {
+			return values, nil
+		}
+	}
+
+	if t.Server.Parent == nil {
+		return nil, nil
+	}
+
+	return t.Server.Parent.Properties[directive], nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getRequestVariables returns map with builtin variables for given request
+func getRequestVariables(req *Request, server *Server) map[string]string {
+	vars := make(map[string]string)
+
+	path, args := req.URI, ""
+
+	if index := strings.IndexByte(req.URI, '?'); index != -1 {
+		path, args = req.URI[:index], req.URI[index+1:]
+	}
+
+	method := req.Method
+
+	if method == "" {
+		method = "GET"
+	}
+
+	vars["request_method"] = method
+	vars["request_uri"] = req.URI
+	vars["uri"] = path
+	vars["document_uri"] = path
+	vars["args"] = args
+	vars["query_string"] = args
+	vars["scheme"] = "http"
+	vars["request"] = method + " " + req.URI + " HTTP/1.1"
+	vars["server_protocol"] = "HTTP/1.1"
+
+	if args != "" {
+		vars["is_args"] = "?"
+
+		for _, arg := range strings.Split(args, "&") {
+			kv := strings.SplitN(arg, "=", 2)
+
+			if len(kv) == 2 {
+				vars["arg_"+kv[0]] = kv[1]
+			}
+		}
+	}
+
+	if req.Scheme == "https" {
+		vars["scheme"] = "https"
+		vars["https"] = "on"
+	}
+
+	if req.RemoteAddr != nil {
+		vars["remote_addr"] = req.RemoteAddr.String()
+	}
+
+	for name, value := range req.Headers {
+		vars["http_"+strings.Replace(strings.ToLower(name), "-", "_", -1)] = value
+	}
+
+	names := server.GetNames()
+
+	if len(names) != 0 {
+		vars["server_name"] = names[0]
+	}
+
+	vars["host"] = strings.ToLower(stripPort(req.Host))
+
+	if vars["host"] == "" {
+		vars["host"] = vars["server_name"]
+	}
+
+	if vars["http_host"] == "" {
+		vars["http_host"] = req.Host
+	}
+
+	return vars
+}
+
+// matchWildcardServer tries to find server with wildcard name matching given host
+func matchWildcardServer(servers []*Server, host string) *Server {
+	var head, tail *Server
+	var headLen, tailLen int
+
+	for _, server := range servers {
+		for _, name := range server.GetNames() {
+			name = strings.ToLower(name)
+
+			switch {
+			case strings.HasPrefix(name, "*."):
+				if strings.HasSuffix(host, name[1:]) && len(name) > headLen {
+					head, headLen = server, len(name)
+				}
+
+			case strings.HasPrefix(name, "."):
+				if (host == name[1:] || strings.HasSuffix(host, name)) && len(name) > headLen {
+					head, headLen = server, len(name)
+				}
+
+			case strings.HasSuffix(name, ".*"):
+				if strings.HasPrefix(host, name[:len(name)-1]) && len(name) > tailLen {
+					tail, tailLen = server, len(name)
+				}
+			}
+		}
+	}
+
+	if head != nil {
+		return head
+	}
+
+	return tail
+}
+
+// stripPort removes port from host name
+func stripPort(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if index := strings.IndexByte(host, ']'); index != -1 {
+			return host[:index+1]
+		}
+	}
+
+	if strings.Count(host, ":") == 1 {
+		return host[:strings.IndexByte(host, ':')]
+	}
+
+	return host
+}
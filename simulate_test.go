@@ -0,0 +1,147 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"net"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestSimulation(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	trace, err := config.HTTP.Simulate(&Request{
+		Scheme:     "https",
+		Host:       "service.domain.com:443",
+		URI:        "/api/v1?id=1",
+		Headers:    map[string]string{"User-Agent": "curl/7.29.0"},
+		RemoteAddr: net.ParseIP("192.168.1.1"),
+	}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Server, Equals, config.HTTP.FindServer("service.domain.com", "https"))
+	c.Assert(trace.Location.URI, Equals, "/")
+	c.Assert(trace.Return, IsNil)
+	c.Assert(trace.URI, Equals, "/api/v1")
+	c.Assert(trace.TargetDirective, Equals, "proxy_pass")
+	c.Assert(trace.Target, Equals, "http://123.0.0.111:80/")
+	c.Assert(trace.Conditions, HasLen, 2)
+	c.Assert(trace.Conditions[0].Result, Equals, false)
+	c.Assert(trace.Conditions[1].Condition, Equals, "$http_user_agent ~* (client2)")
+	c.Assert(trace.Headers, DeepEquals, map[string][]string{
+		"Strict-Transport-Security": {"max-age=32140800"},
+	})
+	c.Assert(trace.ProxyHeaders["Host"], Equals, "service.domain.com")
+	c.Assert(trace.ProxyHeaders["X-Real-IP"], Equals, "192.168.1.1")
+	c.Assert(trace.Variables["arg_id"], Equals, "1")
+
+	trace, err = config.HTTP.Simulate(&Request{
+		Scheme:  "https",
+		Host:    "service.domain.com",
+		URI:     "/",
+		Headers: map[string]string{"User-Agent": "Client2/1.0"},
+	}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Return, DeepEquals, &Return{Code: 403})
+	c.Assert(trace.Conditions[1].Result, Equals, true)
+	c.Assert(trace.Target, Equals, "")
+
+	trace, err = config.HTTP.Simulate(&Request{Scheme: "https", Host: "service.domain.com", URI: "/robots.txt"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Location.Modifier, Equals, "=")
+	c.Assert(trace.TargetDirective, Equals, "root")
+	c.Assert(trace.Target, Equals, "/srv/robots/robots.txt")
+	c.Assert(trace.Headers, HasLen, 1)
+
+	trace, err = config.HTTP.Simulate(&Request{Host: "unknown.domain.com", URI: "/index.html"}, nil)
+
+	c.Assert(err, IsNil)
+	c.Assert(trace.Server.GetNames(), DeepEquals, []string{"_"})
+	c.Assert(trace.Target, Equals, "/usr/share/webkaos/html/index.html")
+	c.Assert(trace.Headers, DeepEquals, map[string][]string{"X-Request-ID": {""}})
+
+	trace.Server.Locations = append(trace.Server.Locations, &Location{URI: "^/(?!static/)", Modifier: "~"})
+
+	_, err = config.HTTP.Simulate(&Request{Host: "unknown.domain.com", URI: "/index.html"}, nil)
+	c.Assert(err, NotNil)
+
+	_, err = config.HTTP.Simulate(&Request{Host: "service.domain.com"}, nil)
+	c.Assert(err, NotNil)
+
+	_, err = (&HTTP{}).Simulate(&Request{URI: "/"}, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *NginxSuite) TestServerMatching(c *C) {
+	http := &HTTP{}
+
+	for _, names := range []string{"example.com", "*.example.com", "mail.*", "~^www\\d+\\.example\\.net$", "_"} {
+		http.Servers = append(http.Servers, &Server{
+			Properties: &ConditionalProperties{
+				Conditions: []string{},
+				Data: map[string][]ConditionalProperty{
+					"server_name": {{-1, names}},
+					"listen":      {{-1, "80"}},
+				},
+			},
+		})
+	}
+
+	c.Assert(http.MatchServer("example.com", "http"), Equals, http.Servers[0])
+	c.Assert(http.MatchServer("EXAMPLE.COM.", "http"), Equals, http.Servers[0])
+	c.Assert(http.MatchServer("api.example.com", "http"), Equals, http.Servers[1])
+	c.Assert(http.MatchServer("mail.example.org", "http"), Equals, http.Servers[2])
+	c.Assert(http.MatchServer("www12.example.net:8080", "http"), Equals, http.Servers[3])
+	c.Assert(http.MatchServer("other.net", "http"), Equals, http.Servers[0])
+	c.Assert(http.MatchServer("other.net", "https"), IsNil)
+
+	server := &Server{
+		Locations: []*Location{
+			{URI: "/", Modifier: ""},
+			{URI: "/images/", Modifier: "^~"},
+			{URI: "/api", Modifier: ""},
+			{URI: "\\.(gif|jpg)$", Modifier: "~*"},
+			{URI: "/exact", Modifier: "="},
+			{URI: "@fallback", Modifier: ""},
+		},
+	}
+
+	for uri, index := range map[string]int{
+		"/exact":        4,
+		"/images/a.gif": 1,
+		"/api/a.JPG":    3,
+		"/api/users":    2,
+		"/exact/1":      0,
+	} {
+		location, err := server.MatchLocation(uri)
+
+		c.Assert(err, IsNil)
+		c.Assert(location, Equals, server.Locations[index], Commentf("URI: %s", uri))
+	}
+
+	location, err := (&Server{}).MatchLocation("/")
+
+	c.Assert(err, IsNil)
+	c.Assert(location, IsNil)
+
+	// Regex locations with PCRE-only syntax can't be matched
+	server.Locations = append(server.Locations, &Location{URI: "^/(?!api)", Modifier: "~"})
+
+	_, err = server.MatchLocation("/users")
+	c.Assert(err, NotNil)
+	location, err = server.MatchLocation("/exact")
+	c.Assert(err, IsNil)
+	c.Assert(location, Equals, server.Locations[4])
+}
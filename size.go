@@ -0,0 +1,84 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	KILOBYTE Size = 1024
+	MEGABYTE Size = 1024 * KILOBYTE
+	GIGABYTE Size = 1024 * MEGABYTE
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Size is size in bytes
+type Size int64
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseSize parses size or offset value (like "512", "16k", "8m" or "1g")
+func ParseSize(data string) (Size, error) {
+	if data == "" {
+		return 0, fmt.Errorf("Size is empty")
+	}
+
+	scale := Size(1)
+	value := data
+
+	switch data[len(data)-1] {
+	case 'k', 'K':
+		scale, value = KILOBYTE, data[:len(data)-1]
+	case 'm', 'M':
+		scale, value = MEGABYTE, data[:len(data)-1]
+	case 'g', 'G':
+		scale, value = GIGABYTE, data[:len(data)-1]
+	}
+
+	if value == "" {
+		return 0, fmt.Errorf("Invalid size %s", data)
+	}
+
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("Invalid size %s", data)
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || Size(size) > Size(math.MaxInt64)/scale {
+		return 0, fmt.Errorf("Size %s is too big", data)
+	}
+
+	return Size(size) * scale, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns size in NGINX format with the shortest unit
+func (s Size) String() string {
+	switch {
+	case s == 0:
+		return "0"
+	case s%GIGABYTE == 0:
+		return strconv.FormatInt(int64(s/GIGABYTE), 10) + "g"
+	case s%MEGABYTE == 0:
+		return strconv.FormatInt(int64(s/MEGABYTE), 10) + "m"
+	case s%KILOBYTE == 0:
+		return strconv.FormatInt(int64(s/KILOBYTE), 10) + "k"
+	}
+
+	return strconv.FormatInt(int64(s), 10)
+}
@@ -0,0 +1,48 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestSizeParsing(c *C) {
+	for data, size := range map[string]Size{
+		"0":                   0,
+		"512":                 512,
+		"16k":                 16 * KILOBYTE,
+		"16K":                 16 * KILOBYTE,
+		"8m":                  8 * MEGABYTE,
+		"1g":                  GIGABYTE,
+		"8589934591g":         8589934591 * GIGABYTE,
+		"9223372036854775807": 9223372036854775807,
+	} {
+		v, err := ParseSize(data)
+
+		c.Assert(err, IsNil, Commentf("Size: %s", data))
+		c.Assert(v, Equals, size, Commentf("Size: %s", data))
+	}
+
+	for _, data := range []string{
+		"", "abc", "10kk", "k", "-1", "+1", "1.5m", "1 k", "2J",
+		"8589934592g", "9223372036854775808",
+	} {
+		_, err := ParseSize(data)
+		c.Assert(err, NotNil, Commentf("Size: %s", data))
+	}
+
+	c.Assert(Size(0).String(), Equals, "0")
+	c.Assert(Size(1000).String(), Equals, "1000")
+	c.Assert(Size(1536).String(), Equals, "1536")
+	c.Assert(Size(16384).String(), Equals, "16k")
+	c.Assert((1536 * KILOBYTE).String(), Equals, "1536k")
+	c.Assert((8 * MEGABYTE).String(), Equals, "8m")
+	c.Assert((4 * GIGABYTE).String(), Equals, "4g")
+}
@@ -0,0 +1,236 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SplitClients contains info about split_clients block
+type SplitClients struct {
+	Source   string
+	Variable string
+	Buckets  []*SplitBucket
+	Parent   *HTTP
+}
+
+// SplitBucket contains split_clients bucket info
+type SplitBucket struct {
+	Percent float64 // Zero for "*" bucket
+	Value   string
+
+	bound uint32
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Evaluate returns value for given key (source expression with expanded
+// variables)
+func (s *SplitClients) Evaluate(key string) string {
+	if s == nil {
+		return ""
+	}
+
+	hash := murmurHash2([]byte(key))
+
+	for _, bucket := range s.Buckets {
+		if hash < bucket.bound || bucket.Percent == 0 {
+			return bucket.Value
+		}
+	}
+
+	return ""
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseSplitClientsBlock parses split_clients block
+func parseSplitClientsBlock(data []string, cursor int, args []string) (int, *SplitClients, error) {
+	if len(args) != 2 {
+		return -1, nil, fmt.Errorf("Split clients block must have source and target variable")
+	}
+
+	varName, size := parseVariableName(args[1])
+
+	if varName == "" || size != len(args[1]) {
+		return -1, nil, fmt.Errorf("Invalid split clients target variable %s", args[1])
+	}
+
+	split := &SplitClients{Source: args[0], Variable: varName}
+	dataLen := len(data)
+
+	for {
+		if cursor >= dataLen {
+			break
+		}
+
+		line := data[cursor]
+
+		if isBlockEnd(line) {
+			err := split.calcBounds()
+
+			if err != nil {
+				return -1, nil, err
+			}
+
+			return cursor + 1, split, nil
+		}
+
+		err := split.parseBucket(parseArgs(cleanData(line)))
+
+		if err != nil {
+			return -1, nil, err
+		}
+
+		cursor++
+	}
+
+	return -1, nil, fmt.Errorf("Can't find block end")
+}
+
+// parseBucket parses split_clients bucket
+func (s *SplitClients) parseBucket(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("Invalid number of parameters in split clients %s entry", s.Variable)
+	}
+
+	bucket := &SplitBucket{Value: args[1]}
+
+	if args[0] != "*" {
+		if !strings.HasSuffix(args[0], "%") {
+			return fmt.Errorf("Invalid percent value %s in split clients %s", args[0], s.Variable)
+		}
+
+		percent, err := parseFixedPoint(strings.TrimSuffix(args[0], "%"), 2)
+
+		if err != nil || percent == 0 {
+			return fmt.Errorf("Invalid percent value %s in split clients %s", args[0], s.Variable)
+		}
+
+		bucket.Percent = float64(percent) / 100
+		bucket.bound = uint32(percent)
+	}
+
+	s.Buckets = append(s.Buckets, bucket)
+
+	return nil
+}
+
+// calcBounds validates percentage table and calculates hash bounds for all
+// buckets (same way as NGINX does)
+func (s *SplitClients) calcBounds() error {
+	var sum, last uint64
+
+	for _, bucket := range s.Buckets {
+		if bucket.Percent == 0 {
+			sum = 10000
+		} else {
+			sum += uint64(bucket.bound)
+		}
+
+		if sum > 10000 {
+			return fmt.Errorf("Percent total is greater than 100%% in split clients %s", s.Variable)
+		}
+
+		if bucket.Percent != 0 {
+			last += uint64(bucket.bound) * 0xffffffff / 10000
+			bucket.bound = uint32(last)
+		}
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseFixedPoint parses fixed point number with given number of digits after
+// the point (same as ngx_atofp)
+func parseFixedPoint(data string, point int) (int64, error) {
+	var value int64
+	var dot bool
+
+	if data == "" {
+		return 0, fmt.Errorf("Value is empty")
+	}
+
+	for _, r := range data {
+		if point == 0 {
+			return 0, fmt.Errorf("Too many digits after the point in %s", data)
+		}
+
+		if r == '.' {
+			if dot {
+				return 0, fmt.Errorf("Invalid number %s", data)
+			}
+
+			dot = true
+			continue
+		}
+
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("Invalid number %s", data)
+		}
+
+		value = value*10 + int64(r-'0')
+
+		if value > 0xffffffff {
+			return 0, fmt.Errorf("Number %s is too big", data)
+		}
+
+		if dot {
+			point--
+		}
+	}
+
+	for ; point > 0; point-- {
+		value *= 10
+	}
+
+	return value, nil
+}
+
+// murmurHash2 calculates MurmurHash2 hash (same as ngx_murmur_hash2)
+func murmurHash2(data []byte) uint32 {
+	const m = 0x5bd1e995
+
+	h := uint32(len(data))
+
+	for len(data) >= 4 {
+		k := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24
+
+		k *= m
+		k ^= k >> 24
+		k *= m
+
+		h *= m
+		h ^= k
+
+		data = data[4:]
+	}
+
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return h
+}
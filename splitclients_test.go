@@ -0,0 +1,91 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestSplitClientsParsing(c *C) {
+	data := []string{
+		`split_clients "${remote_addr}AAA" $variant {`,
+		"50%     .one;",
+		"30.00%  .two;",
+		`*       "";`,
+		"}",
+		"}",
+	}
+
+	_, http, err := parseHTTPBlock(data, 0)
+
+	c.Assert(err, IsNil)
+
+	split := http.Splits["variant"]
+
+	c.Assert(split, NotNil)
+	c.Assert(split.Parent, Equals, http)
+	c.Assert(split.Source, Equals, "${remote_addr}AAA")
+	c.Assert(split.Buckets, HasLen, 3)
+	c.Assert(split.Buckets[0].Percent, Equals, 50.0)
+	c.Assert(split.Buckets[1].Percent, Equals, 30.0)
+	c.Assert(split.Buckets[2].Percent, Equals, 0.0)
+
+	c.Assert(split.Evaluate("ab"), Equals, ".one")
+	c.Assert(split.Evaluate("127.0.0.1AAA"), Equals, ".two")
+	c.Assert(split.Evaluate("192.168.1.15AAA"), Equals, ".two")
+
+	vars := http.GetVariables()
+	c.Assert(vars.Find("remote_addr")[0].Context, Equals, "http/split_clients[$variant]")
+
+	_, split, err = parseSplitClientsBlock([]string{"0.5% .one;", "2.0% .two;", "}"}, 0, []string{"$key", "$v"})
+
+	c.Assert(err, IsNil)
+	c.Assert(split.Buckets[0].Percent, Equals, 0.5)
+	c.Assert(split.Buckets[0].bound, Equals, uint32(21474836))
+	c.Assert(split.Buckets[1].bound, Equals, uint32(107374181))
+	c.Assert(split.Evaluate("ab"), Equals, "")
+
+	var nilSplit *SplitClients
+	c.Assert(nilSplit.Evaluate("test"), Equals, "")
+}
+
+func (s *NginxSuite) TestSplitClientsErrors(c *C) {
+	args := []string{"$key", "$v"}
+
+	_, _, err := parseSplitClientsBlock([]string{"}"}, 0, []string{"$key"})
+	c.Assert(err, NotNil)
+	_, _, err = parseSplitClientsBlock([]string{"}"}, 0, []string{"$key", "v"})
+	c.Assert(err, NotNil)
+	_, _, err = parseSplitClientsBlock([]string{"50% .one;"}, 0, args)
+	c.Assert(err, NotNil)
+	_, _, err = parseSplitClientsBlock([]string{"60% .one;", "50% .two;", "}"}, 0, args)
+	c.Assert(err, NotNil)
+	_, _, err = parseSplitClientsBlock([]string{"60% .one;", "* .two;", "1% .three;", "}"}, 0, args)
+	c.Assert(err, NotNil)
+
+	badBuckets := []string{"50%;", "50 .one;", "0% .one;", "0.555% .one;", "1.2.3% .one;", "A% .one;", "%", "99999999999% .one;"}
+
+	for _, bucket := range badBuckets {
+		_, _, err = parseSplitClientsBlock([]string{bucket, "}"}, 0, args)
+		c.Assert(err, NotNil, Commentf("Bucket: %s", bucket))
+	}
+
+	_, _, err = parseHTTPBlock([]string{"split_clients $a {", "}", "}"}, 0)
+	c.Assert(err, NotNil)
+}
+
+func (s *NginxSuite) TestMurmurHash(c *C) {
+	c.Assert(murmurHash2([]byte("")), Equals, uint32(0))
+	c.Assert(murmurHash2([]byte("a")), Equals, uint32(2456313694))
+	c.Assert(murmurHash2([]byte("ab")), Equals, uint32(446775395))
+	c.Assert(murmurHash2([]byte("abc")), Equals, uint32(324500635))
+	c.Assert(murmurHash2([]byte("abcd")), Equals, uint32(646393889))
+	c.Assert(murmurHash2([]byte("127.0.0.1AAA")), Equals, uint32(3053215307))
+}
@@ -0,0 +1,212 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SSL contains effective SSL/TLS configuration of server
+type SSL struct {
+	Enabled             bool
+	Certificates        []*SSLCertificate
+	Protocols           []string
+	Ciphers             []string
+	PreferServerCiphers bool
+	ECDHCurves          []string
+	SessionCache        []string
+	SessionTimeout      time.Duration
+	SessionTickets      bool
+	Stapling            bool
+	StaplingVerify      bool
+	TrustedCertificate  string
+	VerifyClient        string
+	VerifyDepth         int
+	ClientCertificate   string
+	DHParam             string
+	ConfCommands        map[string]string
+}
+
+// SSLCertificate contains paths to certificate and its private key
+type SSLCertificate struct {
+	Certificate string
+	Key         string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sslDefaults contains default values of SSL directives
+var sslDefaults = map[string]string{
+	"ssl_protocols":             "TLSv1 TLSv1.1 TLSv1.2 TLSv1.3",
+	"ssl_ciphers":               "HIGH:!aNULL:!MD5",
+	"ssl_prefer_server_ciphers": "off",
+	"ssl_ecdh_curve":            "auto",
+	"ssl_session_cache":         "none",
+	"ssl_session_timeout":       "5m",
+	"ssl_session_tickets":       "on",
+	"ssl_stapling":              "off",
+	"ssl_stapling_verify":       "off",
+	"ssl_verify_client":         "off",
+	"ssl_verify_depth":          "1",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetSSL returns effective SSL configuration of server (with values inherited
+// from HTTP block and defaults)
+func (s *Server) GetSSL() (*SSL, error) {
+	if s == nil {
+		return nil, fmt.Errorf("Server is nil")
+	}
+
+	var err error
+
+	ssl := &SSL{ConfCommands: make(map[string]string)}
+
+	ssl.Enabled = containsString(s.GetProtocols(), "ssl") || s.getSSLValue("ssl") == "on"
+	ssl.Certificates, err = s.getSSLCertificates()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ssl.Protocols = strings.Fields(s.getSSLValue("ssl_protocols"))
+	ssl.Ciphers = parseSSLCiphers(s.getSSLValue("ssl_ciphers"))
+	ssl.ECDHCurves = strings.Split(s.getSSLValue("ssl_ecdh_curve"), ":")
+	ssl.SessionCache = strings.Fields(s.getSSLValue("ssl_session_cache"))
+	ssl.TrustedCertificate = s.getSSLValue("ssl_trusted_certificate")
+	ssl.VerifyClient = s.getSSLValue("ssl_verify_client")
+	ssl.ClientCertificate = s.getSSLValue("ssl_client_certificate")
+	ssl.DHParam = s.getSSLValue("ssl_dhparam")
+
+	for _, flag := range []struct {
+		name  string
+		value *bool
+	}{
+		{"ssl_prefer_server_ciphers", &ssl.PreferServerCiphers},
+		{"ssl_session_tickets", &ssl.SessionTickets},
+		{"ssl_stapling", &ssl.Stapling},
+		{"ssl_stapling_verify", &ssl.StaplingVerify},
+	} {
+		*flag.value, err = parseBool(s.getSSLValue(flag.name))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse %s: %v", flag.name, err)
+		}
+	}
+
+	ssl.SessionTimeout, err = ParseTimeSec(s.getSSLValue("ssl_session_timeout"))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse ssl_session_timeout: %v", err)
+	}
+
+	ssl.VerifyDepth, err = strconv.Atoi(s.getSSLValue("ssl_verify_depth"))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse ssl_verify_depth: %v", err)
+	}
+
+	for _, command := range s.getInheritedValues("ssl_conf_command") {
+		args := parseArgs(command)
+
+		if len(args) != 2 {
+			return nil, fmt.Errorf("Invalid ssl_conf_command value %s", command)
+		}
+
+		ssl.ConfCommands[args[0]] = args[1]
+	}
+
+	return ssl, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getSSLCertificates returns certificate/key pairs
+func (s *Server) getSSLCertificates() ([]*SSLCertificate, error) {
+	var result []*SSLCertificate
+
+	certs := s.getSSLValues("ssl_certificate")
+	keys := s.getSSLValues("ssl_certificate_key")
+
+	if len(certs) != len(keys) {
+		return nil, fmt.Errorf(
+			"Number of certificates (%d) doesn't match number of keys (%d)",
+			len(certs), len(keys),
+		)
+	}
+
+	for i := range certs {
+		result = append(result, &SSLCertificate{certs[i], keys[i]})
+	}
+
+	return result, nil
+}
+
+// getSSLValue returns last effective value of SSL directive
+func (s *Server) getSSLValue(name string) string {
+	values := s.getSSLValues(name)
+
+	if len(values) == 0 {
+		return sslDefaults[name]
+	}
+
+	return values[len(values)-1]
+}
+
+// getSSLValues returns all effective values of SSL directive with removed
+// quotes (values are inherited from HTTP block only if directive isn't defined
+// in server)
+func (s *Server) getSSLValues(name string) []string {
+	var result []string
+
+	for _, value := range s.getInheritedValues(name) {
+		result = append(result, unquote(value))
+	}
+
+	return result
+}
+
+// parseSSLCiphers parses OpenSSL cipher list (groups of equally preferred
+// ciphers are kept as one item)
+func parseSSLCiphers(data string) []string {
+	var result []string
+	var group bool
+
+	start := 0
+
+	for i, r := range data {
+		switch r {
+		case '[':
+			group = true
+		case ']':
+			group = false
+		case ':', ' ', ',':
+			if group {
+				continue
+			}
+
+			if i > start {
+				result = append(result, data[start:i])
+			}
+
+			start = i + 1
+		}
+	}
+
+	if start < len(data) {
+		result = append(result, data[start:])
+	}
+
+	return result
+}
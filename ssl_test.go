@@ -0,0 +1,110 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestSSL(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	ssl, err := config.HTTP.FindServer("service.domain.com", "https").GetSSL()
+
+	c.Assert(err, IsNil)
+	c.Assert(ssl.Enabled, Equals, true)
+	c.Assert(ssl.Certificates, DeepEquals, []*SSLCertificate{
+		{"/etc/webkaos/ssl/my-chain.crt", "/etc/webkaos/ssl/my.key"},
+	})
+	c.Assert(ssl.Protocols, DeepEquals, []string{"TLSv1.1", "TLSv1.2", "TLSv1.3"})
+	c.Assert(ssl.Ciphers, HasLen, 5)
+	c.Assert(ssl.Ciphers[0], Equals, "[ECDHE-ECDSA-CHACHA20-POLY1305|ECDHE-RSA-CHACHA20-POLY1305|ECDHE-ECDSA-AES256-GCM-SHA384|ECDHE-RSA-AES256-GCM-SHA384]")
+	c.Assert(ssl.Ciphers[4], Equals, "ECDHE-RSA-AES128-SHA")
+	c.Assert(ssl.PreferServerCiphers, Equals, true)
+	c.Assert(ssl.ECDHCurves, DeepEquals, []string{"X25519", "P-521", "P-384"})
+	c.Assert(ssl.SessionCache, DeepEquals, []string{"shared:SSL:30m"})
+	c.Assert(ssl.SessionTimeout, Equals, 5*time.Minute)
+	c.Assert(ssl.SessionTickets, Equals, true)
+	c.Assert(ssl.Stapling, Equals, false)
+	c.Assert(ssl.VerifyClient, Equals, "off")
+	c.Assert(ssl.VerifyDepth, Equals, 1)
+	c.Assert(ssl.DHParam, Equals, "/etc/webkaos/ssl/dhparam.pem")
+	c.Assert(ssl.ConfCommands, HasLen, 0)
+
+	ssl, err = config.HTTP.FindServer("service.domain.com", "http").GetSSL()
+
+	c.Assert(err, IsNil)
+	c.Assert(ssl.Enabled, Equals, false)
+	c.Assert(ssl.Certificates, IsNil)
+
+	server := &Server{
+		Properties: &ConditionalProperties{
+			Conditions: []string{},
+			Data: map[string][]ConditionalProperty{
+				"ssl":                 {{-1, "on"}},
+				"ssl_certificate":     {{-1, "rsa.crt"}, {-1, `"ecdsa.crt"`}},
+				"ssl_certificate_key": {{-1, "rsa.key"}, {-1, "'ecdsa.key'"}},
+				"ssl_ciphers":         {{-1, "'HIGH:!aNULL:!MD5'"}},
+				"ssl_dhparam":         {{-1, `"/etc/ssl/dh param.pem"`}},
+				"ssl_stapling":        {{-1, "on"}},
+				"ssl_verify_client":   {{-1, "optional"}},
+				"ssl_verify_depth":    {{-1, "2"}},
+				"ssl_conf_command":    {{-1, "Options PrioritizeChaCha"}, {-1, "Ciphersuites 'TLS_AES_128_GCM_SHA256:TLS_AES_256_GCM_SHA384'"}},
+			},
+		},
+	}
+
+	ssl, err = server.GetSSL()
+
+	c.Assert(err, IsNil)
+	c.Assert(ssl.Enabled, Equals, true)
+	c.Assert(ssl.Certificates, HasLen, 2)
+	c.Assert(ssl.Certificates[1], DeepEquals, &SSLCertificate{"ecdsa.crt", "ecdsa.key"})
+	c.Assert(ssl.Protocols, DeepEquals, []string{"TLSv1", "TLSv1.1", "TLSv1.2", "TLSv1.3"})
+	c.Assert(ssl.Ciphers, DeepEquals, []string{"HIGH", "!aNULL", "!MD5"})
+	c.Assert(ssl.DHParam, Equals, "/etc/ssl/dh param.pem")
+	c.Assert(ssl.Stapling, Equals, true)
+	c.Assert(ssl.VerifyClient, Equals, "optional")
+	c.Assert(ssl.VerifyDepth, Equals, 2)
+	c.Assert(ssl.ConfCommands, DeepEquals, map[string]string{
+		"Options":      "PrioritizeChaCha",
+		"Ciphersuites": "TLS_AES_128_GCM_SHA256:TLS_AES_256_GCM_SHA384",
+	})
+
+	server.Properties.Data["ssl_certificate_key"] = []ConditionalProperty{{-1, "rsa.key"}}
+
+	_, err = server.GetSSL()
+	c.Assert(err, NotNil)
+
+	delete(server.Properties.Data, "ssl_certificate_key")
+	delete(server.Properties.Data, "ssl_certificate")
+
+	for name, value := range map[string]string{
+		"ssl_stapling":        "yes",
+		"ssl_session_timeout": "5x",
+		"ssl_verify_depth":    "one",
+		"ssl_conf_command":    "Options",
+	} {
+		prev := server.Properties.Data[name]
+		server.Properties.Data[name] = []ConditionalProperty{{-1, value}}
+
+		_, err = server.GetSSL()
+		c.Assert(err, NotNil, Commentf("Directive: %s", name))
+
+		server.Properties.Data[name] = prev
+	}
+
+	_, err = (*Server)(nil).GetSSL()
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,229 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	DEFAULT_SYSLOG_PORT     = 514
+	DEFAULT_SYSLOG_FACILITY = "local7"
+	DEFAULT_SYSLOG_SEVERITY = "info"
+	DEFAULT_SYSLOG_TAG      = "nginx"
+)
+
+// MAX_SYSLOG_TAG_LENGTH is max length of syslog tag
+const MAX_SYSLOG_TAG_LENGTH = 32
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Syslog contains info about syslog target of access_log or error_log
+type Syslog struct {
+	Host       string // Host or IP address (without brackets for IPv6)
+	Port       int
+	Socket     string // Path to unix socket
+	Facility   string
+	Severity   string
+	Tag        string
+	NoHostname bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// syslogFacilities contains supported syslog facilities
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "intern", "lpr", "news", "uucp",
+	"clock", "authpriv", "ftp", "ntp", "audit", "alert", "cron", "local0",
+	"local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// syslogSeverities contains supported syslog severities
+var syslogSeverities = []string{
+	"emerg", "alert", "crit", "error", "warn", "notice", "info", "debug",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsSyslog returns true if given log target is syslog target
+func IsSyslog(target string) bool {
+	return strings.HasPrefix(target, "syslog:")
+}
+
+// ParseSyslog parses syslog target (e.g. syslog:server=unix:/dev/log,tag=nginx)
+func ParseSyslog(data string) (*Syslog, error) {
+	if !IsSyslog(data) {
+		return nil, fmt.Errorf("Invalid syslog target %s: prefix syslog: is missing", data)
+	}
+
+	syslog := &Syslog{
+		Facility: DEFAULT_SYSLOG_FACILITY,
+		Severity: DEFAULT_SYSLOG_SEVERITY,
+		Tag:      DEFAULT_SYSLOG_TAG,
+	}
+
+	for _, param := range strings.Split(strings.TrimPrefix(data, "syslog:"), ",") {
+		err := syslog.setParameter(param)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid syslog target %s: %v", data, err)
+		}
+	}
+
+	if syslog.Host == "" && syslog.Socket == "" {
+		return nil, fmt.Errorf("Invalid syslog target %s: server is not set", data)
+	}
+
+	return syslog, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsSocket returns true if messages are sent to unix socket
+func (s *Syslog) IsSocket() bool {
+	return s != nil && s.Socket != ""
+}
+
+// Address returns server address
+func (s *Syslog) Address() string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Socket != "":
+		return "unix:" + s.Socket
+	case strings.Contains(s.Host, ":"):
+		return "[" + s.Host + "]:" + strconv.Itoa(s.Port)
+	}
+
+	return s.Host + ":" + strconv.Itoa(s.Port)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// setParameter parses and sets syslog parameter
+func (s *Syslog) setParameter(param string) error {
+	name, value := param, ""
+
+	if index := strings.IndexByte(param, '='); index != -1 {
+		name, value = param[:index], param[index+1:]
+	}
+
+	switch name {
+	case "server":
+		return s.parseServer(value)
+
+	case "facility":
+		if !containsString(syslogFacilities, value) {
+			return fmt.Errorf("Unknown facility %q", value)
+		}
+
+		s.Facility = value
+
+	case "severity":
+		if !containsString(syslogSeverities, value) {
+			return fmt.Errorf("Unknown severity %q", value)
+		}
+
+		s.Severity = value
+
+	case "tag":
+		if value == "" || len(value) > MAX_SYSLOG_TAG_LENGTH {
+			return fmt.Errorf("Tag length must be between 1 and %d", MAX_SYSLOG_TAG_LENGTH)
+		}
+
+		for _, r := range value {
+			if !isSyslogTagChar(r) {
+				return fmt.Errorf("Tag %q contains invalid symbols", value)
+			}
+		}
+
+		s.Tag = value
+
+	case "nohostname":
+		if value != "" || param != "nohostname" {
+			return fmt.Errorf("Parameter nohostname doesn't support value")
+		}
+
+		s.NoHostname = true
+
+	default:
+		return fmt.Errorf("Unknown parameter %q", param)
+	}
+
+	return nil
+}
+
+// parseServer parses server address
+func (s *Syslog) parseServer(data string) error {
+	if strings.HasPrefix(data, "unix:") {
+		s.Socket = strings.TrimPrefix(data, "unix:")
+
+		if s.Socket == "" {
+			return fmt.Errorf("Socket path is empty")
+		}
+
+		return nil
+	}
+
+	host, port := data, ""
+
+	switch {
+	case strings.HasPrefix(data, "["):
+		index := strings.IndexByte(data, ']')
+
+		if index == -1 || (index+1 != len(data) && data[index+1] != ':') {
+			return fmt.Errorf("Invalid IPv6 address %s", data)
+		}
+
+		host, port = data[1:index], strings.TrimPrefix(data[index+1:], ":")
+
+		if port == "" && index+1 != len(data) {
+			return fmt.Errorf("Port is empty")
+		}
+
+	case strings.Count(data, ":") == 1:
+		index := strings.IndexByte(data, ':')
+		host, port = data[:index], data[index+1:]
+
+		if port == "" {
+			return fmt.Errorf("Port is empty")
+		}
+
+	case strings.Count(data, ":") > 1:
+		return fmt.Errorf("IPv6 address %s must be enclosed in brackets", data)
+	}
+
+	if host == "" {
+		return fmt.Errorf("Server address is empty")
+	}
+
+	s.Host, s.Port = host, DEFAULT_SYSLOG_PORT
+
+	if port != "" {
+		num, err := strconv.Atoi(port)
+
+		if err != nil || num < 1 || num > 65535 {
+			return fmt.Errorf("Invalid port %s", port)
+		}
+
+		s.Port = num
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isSyslogTagChar returns true if given symbol can be used in syslog tag
+func isSyslogTagChar(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
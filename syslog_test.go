@@ -0,0 +1,127 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestSyslogParsing(c *C) {
+	syslog, err := ParseSyslog("syslog:server=unix:/dev/log,facility=local7,tag=nginx,severity=info")
+
+	c.Assert(err, IsNil)
+	c.Assert(syslog, DeepEquals, &Syslog{
+		Socket:   "/dev/log",
+		Facility: "local7",
+		Severity: "info",
+		Tag:      "nginx",
+	})
+	c.Assert(syslog.IsSocket(), Equals, true)
+	c.Assert(syslog.Address(), Equals, "unix:/dev/log")
+
+	syslog, err = ParseSyslog("syslog:server=192.168.1.1")
+
+	c.Assert(err, IsNil)
+	c.Assert(syslog, DeepEquals, &Syslog{
+		Host:     "192.168.1.1",
+		Port:     DEFAULT_SYSLOG_PORT,
+		Facility: DEFAULT_SYSLOG_FACILITY,
+		Severity: DEFAULT_SYSLOG_SEVERITY,
+		Tag:      DEFAULT_SYSLOG_TAG,
+	})
+	c.Assert(syslog.IsSocket(), Equals, false)
+	c.Assert(syslog.Address(), Equals, "192.168.1.1:514")
+
+	syslog, err = ParseSyslog("syslog:server=logs.domain.com:12345,facility=auth,severity=crit,tag=web_01,nohostname")
+
+	c.Assert(err, IsNil)
+	c.Assert(syslog.Host, Equals, "logs.domain.com")
+	c.Assert(syslog.Port, Equals, 12345)
+	c.Assert(syslog.Facility, Equals, "auth")
+	c.Assert(syslog.Severity, Equals, "crit")
+	c.Assert(syslog.Tag, Equals, "web_01")
+	c.Assert(syslog.NoHostname, Equals, true)
+
+	syslog, err = ParseSyslog("syslog:server=[::1]:8514")
+
+	c.Assert(err, IsNil)
+	c.Assert(syslog.Host, Equals, "::1")
+	c.Assert(syslog.Port, Equals, 8514)
+	c.Assert(syslog.Address(), Equals, "[::1]:8514")
+
+	syslog, err = ParseSyslog("syslog:server=[2001:db8::1]")
+
+	c.Assert(err, IsNil)
+	c.Assert(syslog.Host, Equals, "2001:db8::1")
+	c.Assert(syslog.Port, Equals, DEFAULT_SYSLOG_PORT)
+
+	for _, data := range []string{
+		"/var/log/access.log",
+		"syslog:",
+		"syslog:facility=local0",
+		"syslog:server=",
+		"syslog:server=unix:",
+		"syslog:server=:514",
+		"syslog:server=localhost:",
+		"syslog:server=localhost:0",
+		"syslog:server=localhost:65536",
+		"syslog:server=::1",
+		"syslog:server=[::1",
+		"syslog:server=[::1]514",
+		"syslog:server=[::1]:",
+		"syslog:server=localhost,facility=local8",
+		"syslog:server=localhost,severity=warning",
+		"syslog:server=localhost,tag=",
+		"syslog:server=localhost,tag=web-01",
+		"syslog:server=localhost,tag=abcdefghijklmnopqrstuvwxyz0123456",
+		"syslog:server=localhost,nohostname=1",
+		"syslog:server=localhost,nohostname=",
+		"syslog:server=localhost,priority=1",
+	} {
+		_, err = ParseSyslog(data)
+		c.Assert(err, NotNil, Commentf("Syslog: %s", data))
+	}
+
+	var nilSyslog *Syslog
+
+	c.Assert(nilSyslog.IsSocket(), Equals, false)
+	c.Assert(nilSyslog.Address(), Equals, "")
+}
+
+func (s *NginxSuite) TestSyslogTargets(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	config.Core["error_log"] = []string{"syslog:server=unix:/dev/log,tag=webkaos warn"}
+	config.HTTP.Properties["access_log"] = []string{"syslog:server=10.0.0.1,facility=local0,severity=notice main"}
+
+	logging, err := config.GetLogging()
+
+	c.Assert(err, IsNil)
+
+	http := logging.Find("http")
+
+	c.Assert(http.AccessLogs[0].Format.Name, Equals, "main")
+	c.Assert(http.AccessLogs[0].Syslog.Address(), Equals, "10.0.0.1:514")
+	c.Assert(http.AccessLogs[0].Syslog.Severity, Equals, "notice")
+	c.Assert(http.ErrorLogs[0].Level, Equals, LOG_LEVEL_WARN)
+	c.Assert(http.ErrorLogs[0].Syslog.Tag, Equals, "webkaos")
+
+	al, err := ParseAccessLog("/var/log/access.log")
+
+	c.Assert(err, IsNil)
+	c.Assert(al.Syslog, IsNil)
+
+	_, err = ParseAccessLog("syslog:server=10.0.0.1,facility=web main")
+	c.Assert(err, NotNil)
+	_, err = ParseErrorLog("syslog:tag=webkaos warn")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,437 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// VariableKind is kind of variable definition
+type VariableKind uint8
+
+const (
+	VAR_UNDEFINED VariableKind = iota
+	VAR_BUILTIN
+	VAR_SET
+	VAR_MAPPED
+	VAR_CAPTURED
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Variable contains info about variable definition or reference. Position of
+// directive is described by context (e.g. http/server[0]/location[/]), because
+// parser doesn't track line numbers.
+type Variable struct {
+	Name      string
+	Kind      VariableKind
+	Directive string
+	Context   string
+}
+
+// Variables contains info about all variables defined and used in http block
+type Variables struct {
+	Definitions []*Variable
+	References  []*Variable
+}
+
+// mappedVariable contains info about variable defined by map-like block
+type mappedVariable struct {
+	Name      string
+	Directive string
+	Context   string
+	Values    []string
+	Regexes   []string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// builtinVariables is slice with names of variables provided by NGINX core and
+// standard modules
+var builtinVariables = []string{
+	"ancient_browser", "arg_", "args", "binary_remote_addr", "body_bytes_sent",
+	"bytes_received", "bytes_sent", "connection", "connection_requests",
+	"connection_time", "connections_active", "connections_reading",
+	"connections_waiting", "connections_writing", "content_length", "content_type",
+	"cookie_", "date_gmt", "date_local", "document_root", "document_uri",
+	"fastcgi_path_info", "fastcgi_script_name", "geoip_", "gzip_ratio", "host",
+	"hostname", "http2", "http3", "http_", "https", "invalid_referer", "is_args",
+	"limit_conn_status", "limit_rate", "limit_req_status", "memcached_key",
+	"modern_browser", "msec", "msie", "nginx_version", "pid", "pipe",
+	"proxy_add_x_forwarded_for", "proxy_host", "proxy_port",
+	"proxy_protocol_addr", "proxy_protocol_port", "proxy_protocol_server_addr",
+	"proxy_protocol_server_port", "proxy_protocol_tlv_", "query_string",
+	"realip_remote_addr", "realip_remote_port", "realpath_root", "remote_addr",
+	"remote_port", "remote_user", "request", "request_body", "request_body_file",
+	"request_completion", "request_filename", "request_id", "request_length",
+	"request_method", "request_time", "request_uri", "scheme", "secure_link",
+	"secure_link_expires", "sent_http_", "sent_trailer_", "server_addr",
+	"server_name", "server_port", "server_protocol", "slice_range", "ssl_",
+	"status", "tcpinfo_rcv_space", "tcpinfo_rtt", "tcpinfo_rttvar",
+	"tcpinfo_snd_cwnd", "time_iso8601", "time_local", "uid_got", "uid_reset",
+	"uid_set", "upstream_addr", "upstream_bytes_received", "upstream_bytes_sent",
+	"upstream_cache_status", "upstream_connect_time", "upstream_cookie_",
+	"upstream_header_time", "upstream_http_", "upstream_last_server_name",
+	"upstream_queue_time", "upstream_response_length", "upstream_response_time",
+	"upstream_status", "upstream_trailer_", "uri",
+}
+
+// setDirectives is slice with directives which define variables
+var setDirectives = []string{"set", "auth_request_set", "js_set", "perl_set"}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetVariables returns info about all variables defined and referenced in http,
+// server and location blocks
+func (h *HTTP) GetVariables() *Variables {
+	result := &Variables{}
+
+	if h == nil {
+		return result
+	}
+
+	h.walk(func(context, name, value string) {
+		result.Definitions = append(result.Definitions, getVariableDefinitions(context, name, value)...)
+	})
+
+	for serverIndex, server := range h.Servers {
+		for _, location := range server.Locations {
+			if strings.HasPrefix(location.Modifier, "~") {
+				context := getLocationContext(getServerContext(serverIndex), location)
+				result.Definitions = append(result.Definitions, getCaptureDefinitions(context, "location", location.URI)...)
+			}
+		}
+	}
+
+	mapped := h.getMappedVariables()
+
+	for _, mv := range mapped {
+		result.Definitions = append(result.Definitions, &Variable{mv.Name, VAR_MAPPED, mv.Directive, mv.Context})
+
+		for _, regex := range mv.Regexes {
+			result.Definitions = append(result.Definitions, getCaptureDefinitions(mv.Context, mv.Directive, regex)...)
+		}
+	}
+
+	h.walk(func(context, name, value string) {
+		result.References = append(result.References, getVariableReferences(context, name, value)...)
+	})
+
+	for _, mv := range mapped {
+		for _, value := range mv.Values {
+			for _, refName := range extractVariables(value) {
+				result.References = append(result.References, &Variable{refName, VAR_UNDEFINED, mv.Directive, mv.Context})
+			}
+		}
+	}
+
+	for _, ref := range result.References {
+		ref.Kind = result.resolve(ref.Name)
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Undefined returns slice with references to undefined variables
+func (v *Variables) Undefined() []*Variable {
+	var result []*Variable
+
+	if v == nil {
+		return nil
+	}
+
+	for _, ref := range v.References {
+		if ref.Kind == VAR_UNDEFINED {
+			result = append(result, ref)
+		}
+	}
+
+	return result
+}
+
+// Find returns all references to variable with given name
+func (v *Variables) Find(name string) []*Variable {
+	var result []*Variable
+
+	if v == nil {
+		return nil
+	}
+
+	name = strings.TrimPrefix(name, "$")
+
+	for _, ref := range v.References {
+		if ref.Name == name {
+			result = append(result, ref)
+		}
+	}
+
+	return result
+}
+
+// resolve returns kind of variable with given name
+func (v *Variables) resolve(name string) VariableKind {
+	if isBuiltinVariable(name) {
+		return VAR_BUILTIN
+	}
+
+	if isNumericVariable(name) {
+		return VAR_CAPTURED
+	}
+
+	for _, def := range v.Definitions {
+		if def.Name == name {
+			return def.Kind
+		}
+	}
+
+	return VAR_UNDEFINED
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns name of variable kind
+func (k VariableKind) String() string {
+	switch k {
+	case VAR_BUILTIN:
+		return "builtin"
+	case VAR_SET:
+		return "set"
+	case VAR_MAPPED:
+		return "mapped"
+	case VAR_CAPTURED:
+		return "captured"
+	}
+
+	return "undefined"
+}
+
+// String returns variable info as a string
+func (v *Variable) String() string {
+	return "$" + v.Name + " (" + v.Kind.String() + ") in " + v.Directive + " at " + v.Context
+}
+
+// getMappedVariables returns info about variables defined by map-like blocks
+func (h *HTTP) getMappedVariables() []*mappedVariable {
+	var result []*mappedVariable
+
+	var maps []string
+
+	for varName := range h.Maps {
+		maps = append(maps, varName)
+	}
+
+	for _, varName := range sortStrings(maps) {
+		m := h.Maps[varName]
+		mv := &mappedVariable{
+			Name:      varName,
+			Directive: "map",
+			Context:   "http/map[$" + varName + "]",
+			Values:    []string{m.Source, m.Default},
+		}
+
+		for _, entry := range m.Entries {
+			mv.Values = append(mv.Values, entry.Value)
+
+			if entry.Type == MAP_ENTRY_REGEX {
+				mv.Regexes = append(mv.Regexes, entry.Key)
+			}
+		}
+
+		result = append(result, mv)
+	}
+
+	var geos []string
+
+	for varName := range h.Geos {
+		geos = append(geos, varName)
+	}
+
+	for _, varName := range sortStrings(geos) {
+		geo := h.Geos[varName]
+		mv := &mappedVariable{
+			Name:      varName,
+			Directive: "geo",
+			Context:   "http/geo[$" + varName + "]",
+			Values:    []string{geo.Source},
+		}
+
+		result = append(result, mv)
+	}
+
+	var splits []string
+
+	for varName := range h.Splits {
+		splits = append(splits, varName)
+	}
+
+	for _, varName := range sortStrings(splits) {
+		split := h.Splits[varName]
+		mv := &mappedVariable{
+			Name:      varName,
+			Directive: "split_clients",
+			Context:   "http/split_clients[$" + varName + "]",
+			Values:    []string{split.Source},
+		}
+
+		for _, bucket := range split.Buckets {
+			mv.Values = append(mv.Values, bucket.Value)
+		}
+
+		result = append(result, mv)
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getVariableDefinitions returns variables defined by given directive
+func getVariableDefinitions(context, name, value string) []*Variable {
+	args := parseArgs(value)
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	if containsString(setDirectives, name) {
+		varName, size := parseVariableName(args[0])
+
+		if varName == "" || size != len(args[0]) {
+			return nil
+		}
+
+		return []*Variable{{varName, VAR_SET, name, context}}
+	}
+
+	var result []*Variable
+
+	switch name {
+	case "if":
+		// Negative matches (!~ and !~*) don't set captures
+		if len(args) == 3 && (args[1] == OP_MATCH || args[1] == OP_MATCH_ICASE) {
+			result = append(result, getCaptureDefinitions(context, name, args[2])...)
+		}
+
+	case "rewrite":
+		result = append(result, getCaptureDefinitions(context, name, args[0])...)
+
+	default:
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "~") {
+				result = append(result, getCaptureDefinitions(context, name, arg)...)
+			}
+		}
+	}
+
+	return result
+}
+
+// getCaptureDefinitions returns variables defined by named captures in given regex
+func getCaptureDefinitions(context, name, regex string) []*Variable {
+	var result []*Variable
+
+	for _, match := range namedGroupRegex.FindAllStringSubmatch(regex, -1) {
+		varName := match[1]
+
+		if varName == "" {
+			varName = match[2]
+		}
+
+		result = append(result, &Variable{varName, VAR_CAPTURED, name, context})
+	}
+
+	return result
+}
+
+// getVariableReferences returns variables referenced by given directive
+func getVariableReferences(context, name, value string) []*Variable {
+	var result []*Variable
+
+	args := parseArgs(value)
+
+	switch {
+	case len(args) == 0:
+		return nil
+
+	case containsString(setDirectives, name), name == "rewrite":
+		// First argument is a variable name or a regular expression
+		args = args[1:]
+
+	case name == "if":
+		// Skip regular expression operand
+		if len(args) == 3 && strings.Contains(args[1], "~") {
+			args = args[:1]
+		}
+	}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "~") {
+			continue
+		}
+
+		for _, varName := range extractVariables(arg) {
+			result = append(result, &Variable{varName, VAR_UNDEFINED, name, context})
+		}
+	}
+
+	return result
+}
+
+// extractVariables returns names of all variables used in given string
+func extractVariables(data string) []string {
+	var result []string
+
+	for {
+		index := strings.IndexByte(data, '$')
+
+		if index == -1 {
+			return result
+		}
+
+		name, size := parseVariableName(data[index:])
+
+		if name == "" {
+			data = data[index+1:]
+			continue
+		}
+
+		result = append(result, name)
+		data = data[index+size:]
+	}
+}
+
+// isBuiltinVariable returns true if variable with given name provided by NGINX
+func isBuiltinVariable(name string) bool {
+	for _, v := range builtinVariables {
+		if name == v || (strings.HasSuffix(v, "_") && strings.HasPrefix(name, v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNumericVariable returns true if given variable is numeric capture ($1..$9)
+func isNumericVariable(name string) bool {
+	return len(name) == 1 && name[0] >= '0' && name[0] <= '9'
+}
+
+// containsString returns true if slice contains given string
+func containsString(data []string, value string) bool {
+	for _, v := range data {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
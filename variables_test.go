@@ -0,0 +1,95 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestVariables(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+	c.Assert(config, NotNil)
+
+	vars := config.HTTP.GetVariables()
+
+	c.Assert(vars.Undefined(), HasLen, 0)
+	c.Assert(vars.Find("$request_id"), Not(HasLen), 0)
+	c.Assert(vars.Find("request_id")[0].Kind, Equals, VAR_BUILTIN)
+
+	data := []string{
+		"log_format test '$remote_addr $custom_var';",
+		"map $http_upgrade $connection_upgrade {",
+		"default upgrade;",
+		"~^(?<proto>web)socket$ $proto;",
+		"}",
+		"server {",
+		"set $backend http://127.0.0.1;",
+		"if ($request_uri ~ ^/(?<section>[a-z]+)/) {",
+		"set $from_if $section;",
+		"}",
+		"if ($uri !~ \"^/$foo\") {",
+		"return 404;",
+		"}",
+		"if ($http_user_agent !~* ^(?<agent>curl)) {",
+		"set $agent_name $agent;",
+		"}",
+		"location ~ ^/api/(?P<version>v[0-9]+)/ {",
+		"proxy_pass $backend/$version$1;",
+		"proxy_set_header Connection $connection_upgrade;",
+		"add_header X-Debug \"$from_if $unknown ${http_x_test}\";",
+		"}",
+		"location / {",
+		"rewrite ^/old/(?'page'.*)$ /new/$page?$args last;",
+		"return 200 $undefined_thing;",
+		"}",
+		"}",
+		"}",
+	}
+
+	_, http, err := parseHTTPBlock(data, 0)
+
+	c.Assert(err, IsNil)
+
+	vars = http.GetVariables()
+	undefined := vars.Undefined()
+
+	c.Assert(undefined, HasLen, 4)
+	c.Assert(undefined[0].Name, Equals, "custom_var")
+	c.Assert(undefined[0].Context, Equals, "http")
+	c.Assert(undefined[1].Name, Equals, "agent")
+	c.Assert(undefined[1].Context, Equals, "http/server[0]/if[2]")
+	c.Assert(undefined[2].Name, Equals, "unknown")
+	c.Assert(undefined[2].Directive, Equals, "add_header")
+	c.Assert(undefined[2].Context, Equals, "http/server[0]/location[~ ^/api/(?P<version>v[0-9]+)/]")
+	c.Assert(undefined[3].String(), Equals, "$undefined_thing (undefined) in return at http/server[0]/location[/]")
+
+	c.Assert(vars.Find("backend")[0].Kind, Equals, VAR_SET)
+	c.Assert(vars.Find("section")[0].Kind, Equals, VAR_CAPTURED)
+	c.Assert(vars.Find("section")[0].Context, Equals, "http/server[0]/if[0]")
+	c.Assert(vars.Find("version")[0].Kind, Equals, VAR_CAPTURED)
+	c.Assert(vars.Find("page")[0].Kind, Equals, VAR_CAPTURED)
+	c.Assert(vars.Find("1")[0].Kind, Equals, VAR_CAPTURED)
+	c.Assert(vars.Find("http_x_test")[0].Kind, Equals, VAR_BUILTIN)
+	c.Assert(vars.Find("request_uri")[0].Kind, Equals, VAR_BUILTIN)
+	c.Assert(vars.Find("connection_upgrade")[0].Kind, Equals, VAR_MAPPED)
+	c.Assert(vars.Find("http_upgrade")[0].Context, Equals, "http/map[$connection_upgrade]")
+	c.Assert(vars.Find("proto")[0].Kind, Equals, VAR_CAPTURED)
+
+	c.Assert(VAR_MAPPED.String(), Equals, "mapped")
+
+	var nilHTTP *HTTP
+	var nilVars *Variables
+
+	c.Assert(nilHTTP.GetVariables().References, HasLen, 0)
+	c.Assert(nilVars.Undefined(), IsNil)
+	c.Assert(nilVars.Find("test"), IsNil)
+}
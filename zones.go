@@ -0,0 +1,315 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Essential Kaos Open Source License <http://essentialkaos.com/ekol?en>         //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SharedZone contains info about shared memory zone
+type SharedZone struct {
+	Name      string
+	Size      Size // Zero size means that zone declared without size (upstream zone)
+	Directive string
+	Context   string
+}
+
+// SharedZones contains info about all shared memory zones
+type SharedZones struct {
+	Zones    []*SharedZone
+	Problems []string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sharedZoneReusable contains directives which zones can be declared many times
+// with the same size (e.g. ssl_session_cache in several servers)
+var sharedZoneReusable = map[string]bool{
+	"ssl_session_cache": true,
+	"zone":              true,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetSharedZones returns inventory of all shared memory zones declared in config
+func (c *Config) GetSharedZones() (*SharedZones, error) {
+	if c == nil {
+		return nil, fmt.Errorf("Config is nil")
+	}
+
+	zones := &SharedZones{}
+
+	err := zones.addProperties("stream", c.Stream)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c.HTTP == nil {
+		zones.check()
+		return zones, nil
+	}
+
+	err = zones.addProperties("http", c.HTTP.Properties)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for serverIndex, server := range c.HTTP.Servers {
+		context := getServerContext(serverIndex)
+
+		for _, prop := range server.Properties.Data["ssl_session_cache"] {
+			err = zones.add(context, "ssl_session_cache", prop.Value)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var upstreams []string
+
+	for name := range c.HTTP.Upstreams {
+		upstreams = append(upstreams, name)
+	}
+
+	for _, name := range sortStrings(upstreams) {
+		for _, value := range c.HTTP.Upstreams[name].Properties["zone"] {
+			err = zones.add("http/upstream["+name+"]", "zone", value)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	zones.check()
+
+	return zones, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns all declarations of zone with given name
+func (z *SharedZones) Get(name string) []*SharedZone {
+	var result []*SharedZone
+
+	if z == nil {
+		return nil
+	}
+
+	for _, zone := range z.Zones {
+		if zone.Name == name {
+			result = append(result, zone)
+		}
+	}
+
+	return result
+}
+
+// TotalSize returns total size of all unique zones
+func (z *SharedZones) TotalSize() Size {
+	var result Size
+
+	if z == nil {
+		return 0
+	}
+
+	sizes := make(map[string]Size)
+
+	for _, zone := range z.Zones {
+		key := zone.getKey()
+
+		if zone.Size > sizes[key] {
+			result += zone.Size - sizes[key]
+			sizes[key] = zone.Size
+		}
+	}
+
+	return result
+}
+
+// IsOK returns true if there are no problems with zones
+func (z *SharedZones) IsOK() bool {
+	return z != nil && len(z.Problems) == 0
+}
+
+// String returns string representation of zone
+func (z *SharedZone) String() string {
+	if z == nil {
+		return ""
+	}
+
+	if z.Size == 0 {
+		return fmt.Sprintf("%s (%s in %s)", z.Name, z.Directive, z.Context)
+	}
+
+	return fmt.Sprintf("%s:%s (%s in %s)", z.Name, z.Size, z.Directive, z.Context)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// addProperties adds zones from block properties
+func (z *SharedZones) addProperties(context string, props Properties) error {
+	for _, name := range []string{
+		"limit_req_zone", "limit_conn_zone", "proxy_cache_path",
+		"fastcgi_cache_path", "uwsgi_cache_path", "scgi_cache_path",
+		"ssl_session_cache", "keyval_zone",
+	} {
+		for _, value := range props[name] {
+			err := z.add(context, name, value)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// add parses directive value and adds declared zones
+func (z *SharedZones) add(context, directive, value string) error {
+	specs, err := getZoneSpecs(directive, value)
+
+	if err != nil {
+		return fmt.Errorf("Invalid %s in %s: %v", directive, context, err)
+	}
+
+	for _, spec := range specs {
+		zone := &SharedZone{Directive: directive, Context: context}
+
+		if directive == "zone" && !strings.Contains(spec, ":") {
+			zone.Name = spec
+		} else {
+			zone.Name, zone.Size, err = parseZoneSpec(spec)
+
+			if err != nil {
+				return fmt.Errorf("Invalid %s in %s: %v", directive, context, err)
+			}
+		}
+
+		z.Zones = append(z.Zones, zone)
+	}
+
+	return nil
+}
+
+// check checks zones for duplicates and conflicts
+func (z *SharedZones) check() {
+	var names []string
+
+	declared := make(map[string]*SharedZone)
+
+	for _, zone := range z.Zones {
+		prev := declared[zone.Name]
+
+		switch {
+		case prev == nil:
+			declared[zone.Name] = zone
+			names = append(names, zone.Name)
+
+		case prev.getKey() != zone.getKey():
+			z.Problems = append(z.Problems, fmt.Sprintf(
+				"Zone %s declared by %s in %s is already declared by %s in %s",
+				zone.Name, zone.Directive, zone.Context, prev.Directive, prev.Context,
+			))
+
+		case !sharedZoneReusable[zone.Directive]:
+			z.Problems = append(z.Problems, fmt.Sprintf(
+				"Zone %s in %s is duplicate of zone declared in %s",
+				zone.Name, zone.Context, prev.Context,
+			))
+
+		case zone.Size != 0 && prev.Size != 0 && zone.Size != prev.Size:
+			z.Problems = append(z.Problems, fmt.Sprintf(
+				"Zone %s in %s has size %s, but it is already declared with size %s in %s",
+				zone.Name, zone.Context, zone.Size, prev.Size, prev.Context,
+			))
+
+		case prev.Size == 0:
+			declared[zone.Name] = zone
+		}
+	}
+
+	for _, name := range sortStrings(names) {
+		if declared[name].Size == 0 {
+			z.Problems = append(z.Problems, fmt.Sprintf(
+				"Zone %s in %s is declared without size", name, declared[name].Context,
+			))
+		}
+	}
+}
+
+// getKey returns zone key used for detecting conflicts between zones
+func (z *SharedZone) getKey() string {
+	module := "http"
+
+	if strings.HasPrefix(z.Context, "stream") {
+		module = "stream"
+	}
+
+	return module + ":" + z.Directive + ":" + z.Name
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getZoneSpecs extracts zone specifications (name:size) from directive value
+func getZoneSpecs(directive, value string) ([]string, error) {
+	var result []string
+
+	args := parseArgs(value)
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Value is empty")
+	}
+
+	switch directive {
+	case "limit_req_zone", "limit_conn_zone", "keyval_zone",
+		"proxy_cache_path", "fastcgi_cache_path", "uwsgi_cache_path", "scgi_cache_path":
+		param := "zone="
+
+		if strings.HasSuffix(directive, "_cache_path") {
+			param = "keys_zone="
+		}
+
+		for _, arg := range args {
+			if strings.HasPrefix(arg, param) {
+				result = append(result, strings.TrimPrefix(arg, param))
+			}
+		}
+
+		if len(result) == 0 {
+			return nil, fmt.Errorf("Zone is not defined")
+		}
+
+	case "ssl_session_cache":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "shared:") {
+				result = append(result, strings.TrimPrefix(arg, "shared:"))
+			}
+		}
+
+	case "zone":
+		if len(args) > 2 {
+			return nil, fmt.Errorf("Invalid number of parameters")
+		}
+
+		if len(args) == 1 {
+			return []string{args[0]}, nil
+		}
+
+		return []string{args[0] + ":" + args[1]}, nil
+	}
+
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package nginx
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2020 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	. "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *NginxSuite) TestSharedZones(c *C) {
+	config, err := Read("testdata/webkaos.conf", "")
+
+	c.Assert(err, IsNil)
+
+	zones, err := config.GetSharedZones()
+
+	c.Assert(err, IsNil)
+	c.Assert(zones.IsOK(), Equals, true)
+	c.Assert(zones.Zones, DeepEquals, []*SharedZone{
+		{"SSL", 30 * MEGABYTE, "ssl_session_cache", "http"},
+	})
+
+	http := config.HTTP
+	http.Properties["limit_req_zone"] = []string{"$binary_remote_addr zone=perip:10m rate=1r/s"}
+	http.Properties["limit_conn_zone"] = []string{"$server_name zone=perserver:1m"}
+	http.Properties["proxy_cache_path"] = []string{"/var/cache levels=1:2 keys_zone=cache:64m"}
+	http.Properties["fastcgi_cache_path"] = []string{"/var/cache/fcgi keys_zone=fcgi:8m"}
+	http.Properties["uwsgi_cache_path"] = []string{"/var/cache/uwsgi keys_zone=uwsgi:4m"}
+	http.Properties["scgi_cache_path"] = []string{"/var/cache/scgi keys_zone=scgi:2m"}
+	http.Properties["keyval_zone"] = []string{"zone=one:32k state=/var/lib/one.keyval"}
+	http.Servers[0].Properties.Data["ssl_session_cache"] = []ConditionalProperty{{-1, "builtin:1000 shared:SSL:30m"}}
+	http.Upstreams["a"] = &Upstream{"a", Properties{"zone": {"backend 1m"}}, http}
+	http.Upstreams["b"] = &Upstream{"b", Properties{"zone": {"backend"}}, http}
+	config.Stream = Properties{"limit_conn_zone": {"$binary_remote_addr zone=addr:1m"}}
+
+	zones, err = config.GetSharedZones()
+
+	c.Assert(err, IsNil)
+	c.Assert(zones.Problems, IsNil)
+	c.Assert(zones.Zones, HasLen, 12)
+	c.Assert(zones.Zones[0].String(), Equals, "addr:1m (limit_conn_zone in stream)")
+	c.Assert(zones.Zones[2].String(), Equals, "perserver:1m (limit_conn_zone in http)")
+	c.Assert(zones.Zones[4].String(), Equals, "fcgi:8m (fastcgi_cache_path in http)")
+	c.Assert(zones.Zones[5].String(), Equals, "uwsgi:4m (uwsgi_cache_path in http)")
+	c.Assert(zones.Zones[6].String(), Equals, "scgi:2m (scgi_cache_path in http)")
+	c.Assert(zones.Zones[11].String(), Equals, "backend (zone in http/upstream[b])")
+	c.Assert(zones.Get("perip"), HasLen, 1)
+	c.Assert(zones.Get("unknown"), IsNil)
+	c.Assert(zones.TotalSize(), Equals, 1*MEGABYTE+10*MEGABYTE+1*MEGABYTE+64*MEGABYTE+8*MEGABYTE+4*MEGABYTE+2*MEGABYTE+30*MEGABYTE+32*KILOBYTE+1*MEGABYTE)
+
+	http.Properties["limit_conn_zone"] = append(http.Properties["limit_conn_zone"], "$host zone=perserver:1m")
+	http.Properties["keyval_zone"] = append(http.Properties["keyval_zone"], "zone=cache:1m")
+	http.Properties["scgi_cache_path"] = append(http.Properties["scgi_cache_path"], "/var/cache/fcgi keys_zone=fcgi:8m")
+	http.Servers[0].Properties.Data["ssl_session_cache"] = []ConditionalProperty{{-1, "shared:SSL:10m"}}
+	http.Upstreams["c"] = &Upstream{"c", Properties{"zone": {"lonely"}}, http}
+
+	zones, err = config.GetSharedZones()
+
+	c.Assert(err, IsNil)
+	c.Assert(zones.IsOK(), Equals, false)
+	c.Assert(zones.Problems, DeepEquals, []string{
+		"Zone perserver in http is duplicate of zone declared in http",
+		"Zone fcgi declared by scgi_cache_path in http is already declared by fastcgi_cache_path in http",
+		"Zone cache declared by keyval_zone in http is already declared by proxy_cache_path in http",
+		"Zone SSL in http/server[0] has size 10m, but it is already declared with size 30m in http",
+		"Zone lonely in http/upstream[c] is declared without size",
+	})
+
+	for _, value := range []string{"", "a b c", "a 0"} {
+		http.Upstreams["c"].Properties["zone"] = []string{value}
+		_, err = config.GetSharedZones()
+		c.Assert(err, NotNil, Commentf("Zone: %s", value))
+	}
+
+	delete(http.Upstreams, "c")
+	http.Properties["limit_req_zone"] = []string{"$binary_remote_addr rate=1r/s"}
+
+	_, err = config.GetSharedZones()
+	c.Assert(err, NotNil)
+
+	http.Properties["limit_req_zone"] = nil
+	http.Properties["uwsgi_cache_path"] = []string{"/var/cache/uwsgi levels=1:2"}
+
+	_, err = config.GetSharedZones()
+	c.Assert(err, NotNil)
+
+	config.Stream = Properties{"keyval_zone": {""}}
+
+	_, err = config.GetSharedZones()
+	c.Assert(err, NotNil)
+
+	config.Stream = Properties{"keyval_zone": {"zone=one:32k"}}
+	config.HTTP = nil
+
+	zones, err = config.GetSharedZones()
+	c.Assert(err, IsNil)
+	c.Assert(zones.TotalSize(), Equals, 32*KILOBYTE)
+
+	var nilZones *SharedZones
+	var nilZone *SharedZone
+
+	c.Assert(nilZones.Get("one"), IsNil)
+	c.Assert(nilZones.TotalSize(), Equals, Size(0))
+	c.Assert(nilZones.IsOK(), Equals, false)
+	c.Assert(nilZone.String(), Equals, "")
+
+	_, err = (*Config)(nil).GetSharedZones()
+	c.Assert(err, NotNil)
+}